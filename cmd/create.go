@@ -4,27 +4,96 @@ Copyright © 2024 Eric Culley <https://github.com/ericulley>
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ericulley/ascii/tui"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 )
 
+var prompt string
+var promptFile string
+var output string
+var jsonOutput bool
+var minimal bool
+var resume string
+
 // chatCmd represents the chat command
 var createCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Opens a chat session with AI to generate an ascii art",
 	Run: func(cmd *cobra.Command, args []string) {
-		p := tea.NewProgram(tui.NewChatModel())
-		if _, err := p.Run(); err != nil {
+		if promptFile != "" {
+			text, err := os.ReadFile(promptFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Oof: could not read the prompt file: %v\n", err)
+				os.Exit(1)
+			}
+			prompt = strings.TrimRight(string(text), "\r\n")
+			if prompt == "" {
+				fmt.Fprintf(os.Stderr, "Oof: the prompt file %s is empty\n", promptFile)
+				os.Exit(1)
+			}
+		}
+		if prompt != "" {
+			generate()
+			return
+		}
+		model := tui.NewChatModel()
+		if minimal {
+			model = model.WithMinimal()
+		}
+		if resume != "" {
+			var err error
+			if model, err = model.Resume(resume); err != nil {
+				fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if err := tui.Run(model); err != nil {
 			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+			return
 		}
+		tui.ClearRecovery()
 	},
 }
 
+// generate runs a single prompt without opening the chat and prints the art,
+// or writes it to --output.
+func generate() {
+	gen, err := tui.Generate(tui.NewChatClient(), prompt)
+	result := ""
+	if jsonOutput {
+		out, _ := json.MarshalIndent(gen, "", "  ")
+		result = string(out)
+	} else if err == nil {
+		result = gen.Art
+	}
+	if result != "" && output != "" {
+		if writeErr := os.WriteFile(output, []byte(result+"\n"), 0o644); writeErr != nil {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", writeErr)
+			os.Exit(1)
+		}
+	} else if result != "" {
+		fmt.Println(result)
+	}
+	if err != nil {
+		if !jsonOutput {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(createCmd)
+	createCmd.Flags().StringVarP(&prompt, "prompt", "p", "", "Generate art from this prompt and print it without opening a chat")
+	createCmd.Flags().StringVar(&promptFile, "prompt-file", "", "Read the prompt for --prompt from this file instead")
+	createCmd.Flags().StringVarP(&output, "output", "o", "", "Write the result of --prompt to this file instead of printing it")
+	createCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the result of --prompt as JSON")
+	createCmd.Flags().BoolVarP(&minimal, "minimal", "m", false, "Hide everything but the conversation, for clean screenshots (toggle with alt+m)")
+	createCmd.Flags().StringVar(&resume, "resume", "", "Carry on with the saved conversation with this id (pick one with alt+h in the chat)")
 }
@@ -0,0 +1,118 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	db "github.com/ericulley/ascii/data"
+	"github.com/ericulley/ascii/tui"
+	"github.com/spf13/cobra"
+)
+
+var exportArt string
+var exportOutput string
+var exportFormats string
+var foreground string
+var background string
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports saved ascii art to files in one or more formats",
+	Run: func(cmd *cobra.Command, args []string) {
+		if exportArt == "" {
+			fmt.Println("Please specify the name or id of the ascii art to export [--art]")
+			return
+		}
+		record, err := db.ArtByNameOrId(exportArt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+			os.Exit(1)
+		}
+		formats := exportFormatList()
+		// Every format shares the base name, each with its own extension
+		base := exportOutput
+		if base == "" {
+			base = record.Name
+		}
+		written, errs := exportAll(base, formats, record.Art)
+		for _, path := range written {
+			fmt.Println("Exported art to " + path)
+		}
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+		}
+		if len(errs) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// exportAll writes art to a file for each format, sharing base as the name
+// with the format as the extension. It returns the paths written and what
+// went wrong with the rest.
+func exportAll(base string, formats []string, art string) ([]string, []error) {
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	written, errs := []string{}, []error{}
+	for _, format := range formats {
+		path := base + "." + format
+		if err := exportTo(path, format, art); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		written = append(written, path)
+	}
+	return written, errs
+}
+
+// exportFormatList returns the formats to export to, from --format or else
+// ASCII_EXPORT_FORMATS, both comma separated. SVG only by default.
+func exportFormatList() []string {
+	list := exportFormats
+	if list == "" {
+		list = os.Getenv("ASCII_EXPORT_FORMATS")
+	}
+	formats := []string{}
+	for _, format := range strings.Split(list, ",") {
+		if format = strings.ToLower(strings.TrimSpace(format)); format != "" {
+			formats = append(formats, format)
+		}
+	}
+	if len(formats) == 0 {
+		return []string{"svg"}
+	}
+	return formats
+}
+
+// exportTo writes art to path in the given format.
+func exportTo(path string, format string, art string) error {
+	var out []byte
+	switch format {
+	case "svg":
+		out = []byte(tui.SVG(art, foreground, background))
+	case "txt":
+		out = []byte(tui.PlainText(art))
+	case "png":
+		var err error
+		if out, err = tui.PNG(art, foreground, background); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format %q, use svg, txt or png", format)
+	}
+	return os.WriteFile(path, out, 0o644)
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVarP(&exportArt, "art", "a", "", "Specify the name or id of the ascii art to export")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Specify the base name of the files to write, the art's name by default")
+	exportCmd.Flags().StringVar(&exportFormats, "format", "", "Specify comma separated formats to export to (svg, txt, png)")
+	exportCmd.Flags().StringVar(&foreground, "fg", "#e6e6e6", "Specify the color of the art")
+	exportCmd.Flags().StringVar(&background, "bg", "#1e1e1e", "Specify the background color")
+}
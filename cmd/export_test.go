@@ -0,0 +1,129 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package cmd
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportAll(t *testing.T) {
+	art := "```\n/\\_/\\\n( o.o )\n```"
+	tests := []struct {
+		name     string
+		base     string
+		formats  []string
+		want     []string
+		wantErrs []string
+	}{
+		{name: "svg", base: "cat", formats: []string{"svg"}, want: []string{"cat.svg"}},
+		{name: "every format", base: "cat", formats: []string{"svg", "txt"}, want: []string{"cat.svg", "cat.txt"}},
+		{name: "extension replaced", base: "cat.svg", formats: []string{"txt", "svg"}, want: []string{"cat.txt", "cat.svg"}},
+		{name: "png", base: "cat", formats: []string{"txt", "png", "svg"}, want: []string{"cat.txt", "cat.png", "cat.svg"}},
+		{name: "unknown", base: "cat", formats: []string{"gif"}, want: []string{}, wantErrs: []string{`unsupported format "gif"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			written, errs := exportAll(filepath.Join(dir, tt.base), tt.formats, art)
+			want := []string{}
+			for _, name := range tt.want {
+				want = append(want, filepath.Join(dir, name))
+			}
+			if !reflect.DeepEqual(written, want) {
+				t.Errorf("exportAll() wrote %v, want %v", written, want)
+			}
+			for _, path := range want {
+				b, err := os.ReadFile(path)
+				if err != nil {
+					t.Errorf("reading %s: %v", path, err)
+				} else if filepath.Ext(path) == ".png" {
+					if _, err := png.Decode(bytes.NewReader(b)); err != nil {
+						t.Errorf("%s isn't a png: %v", path, err)
+					}
+				} else if !strings.Contains(string(b), "o.o") {
+					t.Errorf("%s doesn't have the art:\n%s", path, b)
+				}
+			}
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("exportAll() errors = %v, want %d", errs, len(tt.wantErrs))
+			}
+			for i, err := range errs {
+				if !strings.Contains(err.Error(), tt.wantErrs[i]) {
+					t.Errorf("error = %q, want it to mention %q", err, tt.wantErrs[i])
+				}
+			}
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != len(want) {
+				t.Errorf("%d files in the directory, want %d", len(entries), len(want))
+			}
+		})
+	}
+}
+
+func TestExportPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cat.png")
+	foreground, background = "#fff", "#1e1e1e"
+	if err := exportTo(path, "png", "```\n|\n|||\n```"); err != nil {
+		t.Fatalf("exportTo() = %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("decoding the png: %v", err)
+	}
+	// Three cells across and two lines down, with padding all around
+	if size := img.Bounds().Size(); size.X != 3*7+2*13 || size.Y != 2*13+2*13 {
+		t.Errorf("png is %v, want it sized to the widest line", size)
+	}
+	if r, g, b, _ := img.At(0, 0).RGBA(); r>>8 != 0x1e || g>>8 != 0x1e || b>>8 != 0x1e {
+		t.Errorf("corner is %v, want the background", img.At(0, 0))
+	}
+	drawn := false
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			drawn = drawn || r>>8 == 0xff && g>>8 == 0xff && b>>8 == 0xff
+		}
+	}
+	if !drawn {
+		t.Error("no pixel has the foreground color, want the art drawn")
+	}
+
+	foreground = "white"
+	if err := exportTo(path, "png", "|"); err == nil || !strings.Contains(err.Error(), "isn't a hex color") {
+		t.Errorf("exportTo() with --fg white = %v, want the color turned down", err)
+	}
+	foreground, background = "#e6e6e6", "#1e1e1e"
+}
+
+func TestExportFormatList(t *testing.T) {
+	tests := []struct {
+		flag string
+		env  string
+		want []string
+	}{
+		{want: []string{"svg"}},
+		{env: "txt, SVG", want: []string{"txt", "svg"}},
+		{flag: "txt", env: "svg", want: []string{"txt"}},
+		{flag: " , ", want: []string{"svg"}},
+	}
+	for _, tt := range tests {
+		exportFormats = tt.flag
+		t.Setenv("ASCII_EXPORT_FORMATS", tt.env)
+		if got := exportFormatList(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("exportFormatList() with --format %q and ASCII_EXPORT_FORMATS %q = %v, want %v", tt.flag, tt.env, got, tt.want)
+		}
+	}
+	exportFormats = ""
+}
@@ -0,0 +1,34 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	db "github.com/ericulley/ascii/data"
+	"github.com/ericulley/ascii/tui"
+
+	"github.com/spf13/cobra"
+)
+
+// galleryCmd represents the gallery command
+var galleryCmd = &cobra.Command{
+	Use:   "gallery",
+	Short: "Browse saved ascii art and mark favorites",
+	Run: func(cmd *cobra.Command, args []string) {
+		records, err := db.ListArtRecords()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+			os.Exit(1)
+		}
+		if err := tui.Run(tui.NewGalleryModel(records)); err != nil {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(galleryCmd)
+}
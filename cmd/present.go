@@ -0,0 +1,92 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	db "github.com/ericulley/ascii/data"
+	"github.com/ericulley/ascii/tui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+var file string
+var dir string
+var interval int
+var generatePrompt string
+
+// presentCmd represents the present command
+var presentCmd = &cobra.Command{
+	Use:   "present",
+	Short: "Displays ascii art full-screen until you quit (q)",
+	Run: func(cmd *cobra.Command, args []string) {
+		arts, err := presentArts()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+			os.Exit(1)
+		}
+		if len(arts) == 0 {
+			fmt.Println("No ascii art found to present")
+			return
+		}
+		if err := tui.Run(tui.NewPresentModel(arts, time.Duration(interval)*time.Second), tea.WithAltScreen()); err != nil {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+		}
+	},
+}
+
+// presentArts loads the art to present from a file, a directory of files, art
+// generated once for a prompt, or otherwise a random piece from the database.
+func presentArts() ([]string, error) {
+	if generatePrompt != "" {
+		gen, err := tui.Generate(tui.NewChatClient(), generatePrompt)
+		if err != nil {
+			return nil, err
+		}
+		return []string{gen.Art}, nil
+	}
+	if file != "" {
+		art, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return []string{string(art)}, nil
+	}
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		arts := []string{}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			art, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			arts = append(arts, string(art))
+		}
+		return arts, nil
+	}
+	art, err := db.RandomArt()
+	if err != nil {
+		return nil, err
+	}
+	return []string{art}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(presentCmd)
+	presentCmd.Flags().StringVarP(&file, "file", "f", "", "Specify a file containing the ascii art to present")
+	presentCmd.Flags().StringVarP(&dir, "dir", "d", "", "Specify a directory of ascii art files to cycle through")
+	presentCmd.Flags().StringVarP(&generatePrompt, "generate", "g", "", "Generate ascii art once for a prompt and present it")
+	presentCmd.Flags().IntVarP(&interval, "interval", "i", 10, "Seconds to show each art when presenting a directory")
+}
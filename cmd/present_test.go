@@ -0,0 +1,25 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPresentArtsGenerate(t *testing.T) {
+	// Without a key the example art comes back, so nothing is sent anywhere
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEYS", "")
+	t.Setenv("ASCII_EXAMPLES_DIR", "")
+	generatePrompt = "a cat"
+	t.Cleanup(func() { generatePrompt = "" })
+	arts, err := presentArts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(arts) != 1 || strings.TrimSpace(arts[0]) == "" || strings.HasPrefix(arts[0], "```") {
+		t.Errorf("presentArts() = %q, want one piece of generated art without its fence", arts)
+	}
+}
@@ -0,0 +1,34 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ericulley/ascii/tui"
+
+	"github.com/spf13/cobra"
+)
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay <transcript.md>",
+	Short: "Steps through a saved chat transcript one exchange at a time",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		model, err := tui.NewReplayModel(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not load transcript: %v\n", err)
+			os.Exit(1)
+		}
+		if err := tui.Run(model); err != nil {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+}
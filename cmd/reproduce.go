@@ -0,0 +1,38 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	db "github.com/ericulley/ascii/data"
+	"github.com/ericulley/ascii/tui"
+
+	"github.com/spf13/cobra"
+)
+
+// reproduceCmd represents the reproduce command
+var reproduceCmd = &cobra.Command{
+	Use:   "reproduce <name or id>",
+	Short: "Generates saved ascii art again with the prompt, model, seed and settings it was saved with",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		record, err := db.ArtByNameOrId(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+			os.Exit(1)
+		}
+		gen, err := tui.Reproduce(tui.NewChatClient(), record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(gen.Art)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reproduceCmd)
+}
@@ -6,10 +6,14 @@ package cmd
 import (
 	"os"
 
+	"github.com/charmbracelet/lipgloss"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
 )
 
+var noColor bool
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "ascii",
@@ -17,6 +21,13 @@ var rootCmd = &cobra.Command{
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// lipgloss already falls back to plain text for NO_COLOR and terminals
+		// without color support, this lets it be forced as well
+		if noColor {
+			lipgloss.SetColorProfile(termenv.Ascii)
+		}
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -30,4 +41,5 @@ func Execute() {
 
 func init() {
 	rootCmd.Flags().BoolP("toggle", "t", false, "help message for toggle")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colors in the interface (NO_COLOR is also honored)")
 }
@@ -0,0 +1,80 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/ericulley/ascii/tui"
+	"github.com/spf13/cobra"
+)
+
+var host string
+var port int
+
+type generateRequest struct {
+	Prompt string `json:"prompt"`
+}
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Starts an HTTP server that generates ascii art on POST /generate",
+	Run: func(cmd *cobra.Command, args []string) {
+		client := tui.NewChatClient()
+		mux := http.NewServeMux()
+		mux.HandleFunc("/generate", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var req generateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Prompt == "" {
+				http.Error(w, `expected a JSON body like {"prompt": "a dog"}`, http.StatusBadRequest)
+				return
+			}
+			gen, err := tui.Generate(client, req.Prompt)
+			w.Header().Set("Content-Type", "application/json")
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+			json.NewEncoder(w).Encode(gen)
+		})
+
+		srv := &http.Server{
+			Addr:    net.JoinHostPort(host, strconv.Itoa(port)),
+			Handler: mux,
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			// Let in-flight generations finish before exiting
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdownCtx)
+		}()
+
+		fmt.Println("Listening on http://" + srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintf(os.Stderr, "Oof: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&host, "host", "localhost", "Specify the address to listen on")
+	serveCmd.Flags().IntVarP(&port, "port", "p", 8080, "Specify the port to listen on")
+}
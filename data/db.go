@@ -8,11 +8,100 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 )
 
 type AsciiRecord struct {
-	Name string
-	Art  string
+	Id       int
+	Name     string
+	Art      string
+	Favorite bool
+	// Metadata about how the art was generated, nil when unknown
+	Seed *int
+	// The style the art was asked for in, empty when unknown
+	Style string
+	// The rest of what the art was generated with, to reproduce it. Empty,
+	// nil or 0 when unknown
+	Model       string
+	Prompt      string
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+}
+
+// metadataColumns are added to databases created before they existed.
+var metadataColumns = []string{
+	"seed INTEGER",
+	"favorite INTEGER NOT NULL DEFAULT 0",
+	"style TEXT",
+	"model TEXT",
+	"prompt TEXT",
+	"temperature REAL",
+	"top_p REAL",
+	"max_tokens INTEGER",
+}
+
+// recordColumns are the columns a whole record is read from, in the order
+// scanRecord expects.
+const recordColumns = `id, name, art, favorite, seed, style, model, prompt, temperature, top_p, max_tokens`
+
+// scanRecord reads a record selected with recordColumns, leaving out any
+// metadata that wasn't saved with it.
+func scanRecord(row interface{ Scan(...any) error }) (AsciiRecord, error) {
+	var record AsciiRecord
+	var seed, maxTokens sql.NullInt64
+	var style, model, prompt sql.NullString
+	var temperature, topP sql.NullFloat64
+	if err := row.Scan(&record.Id, &record.Name, &record.Art, &record.Favorite, &seed, &style, &model, &prompt, &temperature, &topP, &maxTokens); err != nil {
+		return AsciiRecord{}, err
+	}
+	if seed.Valid {
+		s := int(seed.Int64)
+		record.Seed = &s
+	}
+	record.Style = style.String
+	record.Model = model.String
+	record.Prompt = prompt.String
+	if temperature.Valid {
+		record.Temperature = &temperature.Float64
+	}
+	if topP.Valid {
+		record.TopP = &topP.Float64
+	}
+	record.MaxTokens = int(maxTokens.Int64)
+	return record, nil
+}
+
+/*
+ *  Schema function
+ */
+func ensureSchema(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS ascii (id INTEGER PRIMARY KEY, name TEXT, art TEXT)`); err != nil {
+		return err
+	}
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('ascii')`)
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[column] = true
+	}
+	rows.Close()
+	for _, column := range metadataColumns {
+		if existing[strings.Fields(column)[0]] {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE ascii ADD COLUMN ` + column); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 /*
@@ -24,11 +113,19 @@ func SaveArtToDB(ascii AsciiRecord) error {
 		log.Fatal(err)
 	}
 	defer db.Close()
-	stmt, err := db.Prepare(`INSERT INTO ascii (id, name, art) VALUES (?, ?, ?)`)
+	if err := ensureSchema(db); err != nil {
+		return err
+	}
+	stmt, err := db.Prepare(`INSERT INTO ascii (id, name, art, seed, style, model, prompt, temperature, top_p, max_tokens) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		log.Fatal(err)
 	}
-	res, err := stmt.Exec(nil, ascii.Name, ascii.Art)
+	res, err := stmt.Exec(nil, ascii.Name, ascii.Art, ascii.Seed,
+		sql.NullString{String: ascii.Style, Valid: ascii.Style != ""},
+		sql.NullString{String: ascii.Model, Valid: ascii.Model != ""},
+		sql.NullString{String: ascii.Prompt, Valid: ascii.Prompt != ""},
+		ascii.Temperature, ascii.TopP,
+		sql.NullInt64{Int64: int64(ascii.MaxTokens), Valid: ascii.MaxTokens > 0})
 	if err != nil {
 		return err
 	}
@@ -48,7 +145,7 @@ func ListAllArt() error {
 		log.Fatal(err)
 	}
 	defer db.Close()
-	rows, err := db.Query(`SELECT * FROM ascii`)
+	rows, err := db.Query(`SELECT id, name, art FROM ascii`)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -75,7 +172,7 @@ func ListArtWithLimit(limit int) error {
 		log.Fatal(err)
 	}
 	defer db.Close()
-	rows, err := db.Query(`SELECT * FROM ascii LIMIT ?`, limit)
+	rows, err := db.Query(`SELECT id, name, art FROM ascii LIMIT ?`, limit)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -136,6 +233,47 @@ func ListArtNamesWithLimit(limit int) error {
 	return nil
 }
 
+func ListArtRecords() ([]AsciiRecord, error) {
+	db, err := sql.Open("sqlite3", "./data/sqlite.db")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := ensureSchema(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT ` + recordColumns + ` FROM ascii`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	records := []AsciiRecord{}
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+/*
+ *  Favorite function
+ */
+func SetFavorite(id int, favorite bool) error {
+	db, err := sql.Open("sqlite3", "./data/sqlite.db")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := ensureSchema(db); err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE ascii SET favorite = ? WHERE id = ?`, favorite, id)
+	return err
+}
+
 /*
  *  Delete functions
  */
@@ -234,25 +372,61 @@ func UpdateArt(fromNameOrId string, toName string) error {
 	return nil
 }
 
+/*
+ *  Get function
+ */
+func ArtByNameOrId(nameOrId string) (AsciiRecord, error) {
+	db, err := sql.Open("sqlite3", "./data/sqlite.db")
+	if err != nil {
+		return AsciiRecord{}, err
+	}
+	defer db.Close()
+	if err := ensureSchema(db); err != nil {
+		return AsciiRecord{}, err
+	}
+	query := `SELECT ` + recordColumns + ` FROM ascii WHERE name = ?`
+	var arg any = nameOrId
+	if id, err := strconv.Atoi(nameOrId); err == nil {
+		query = `SELECT ` + recordColumns + ` FROM ascii WHERE id = ?`
+		arg = id
+	}
+	record, err := scanRecord(db.QueryRow(query, arg))
+	if err == sql.ErrNoRows {
+		return AsciiRecord{}, fmt.Errorf("no record found with name or id: %s", nameOrId)
+	}
+	if err != nil {
+		return AsciiRecord{}, err
+	}
+	return record, nil
+}
+
 /*
  *  Art function (generates random ascii art from db)
  */
 func Art() string {
-	db, err := sql.Open("sqlite3", "./data/sqlite.db")
+	art, err := RandomArt()
 	if err != nil {
 		log.Fatal(err)
 	}
+	fmt.Println(art)
+	return art
+}
+
+func RandomArt() (string, error) {
+	db, err := sql.Open("sqlite3", "./data/sqlite.db")
+	if err != nil {
+		return "", err
+	}
 	defer db.Close()
 	stmt, err := db.Prepare(`SELECT art FROM ascii ORDER BY RANDOM() LIMIT 1`)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 	defer stmt.Close()
 	var art string
 	err = stmt.QueryRow().Scan(&art)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
-	fmt.Println(art)
-	return art
+	return art, nil
 }
@@ -7,11 +7,11 @@ import (
 	"log"
 
 	"github.com/ericulley/ascii/cmd"
-	"github.com/joho/godotenv"
+	"github.com/ericulley/ascii/tui"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
+	if err := tui.LoadConfig(); err != nil {
 		log.Fatal("Error loading .env file")
 	}
 	cmd.Execute()
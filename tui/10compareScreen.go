@@ -0,0 +1,118 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type compareModel struct {
+	titles []string
+	arts   []string
+	// The art shown on each side, and which side up/down changes
+	left   int
+	right  int
+	active int
+	width  int
+	height int
+	// Where to go back to, the chat session or the gallery
+	chat    *chatModel
+	gallery *galleryModel
+}
+
+// NewCompareModel shows two of several pieces of art side by side, the last
+// two to start with, to help choose between them.
+func NewCompareModel(titles []string, arts []string) compareModel {
+	return compareModel{
+		titles:  titles,
+		arts:    arts,
+		left:    max(0, len(arts)-2),
+		right:   len(arts) - 1,
+		active:  1,
+		width:   80,
+		height:  10,
+		chat:    nil,
+		gallery: nil,
+	}
+}
+
+func (m compareModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m compareModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+		case "esc":
+			if m.chat != nil {
+				return returnToChat(*m.chat)
+			}
+			if m.gallery != nil {
+				return *m.gallery, nil
+			}
+			return m, tea.Quit
+		case "left", "h":
+			m.active = 0
+		case "right", "l":
+			m.active = 1
+		case "tab":
+			m.active = 1 - m.active
+		case "up", "k":
+			m.step(-1)
+		case "down", "j":
+			m.step(1)
+		}
+	}
+	return m, nil
+}
+
+// step shows the previous or next art on the active side, wrapping around.
+func (m *compareModel) step(delta int) {
+	side := &m.left
+	if m.active == 1 {
+		side = &m.right
+	}
+	*side = (*side + delta + len(m.arts)) % len(m.arts)
+}
+
+func (m compareModel) View() string {
+	if len(m.arts) == 0 {
+		return "There's no art to compare.\n"
+	}
+	help := statusStyle.Render("←/→ to pick a side • ↑/↓ to change its art • esc to go back")
+	return sideBySide(
+		m.label(m.left), m.arts[m.left],
+		m.label(m.right), m.arts[m.right],
+		m.active,
+	) + "\n\n" + help + "\n"
+}
+
+func (m compareModel) label(i int) string {
+	return fmt.Sprintf("%d/%d %s", i+1, len(m.arts), m.titles[i])
+}
+
+// sideBySide lays two pieces of art out next to each other, each under its
+// label and aligned at the top. The shorter one is padded so both frames are
+// the same height, and the active one is highlighted.
+func sideBySide(leftLabel string, left string, rightLabel string, right string, active int) string {
+	left = leftLabel + "\n" + stripFence(left)
+	right = rightLabel + "\n" + stripFence(right)
+	height := max(lipgloss.Height(left), lipgloss.Height(right))
+	styles := []lipgloss.Style{previewStyle, previewStyle}
+	styles[active] = sheetSelectedStyle
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		styles[0].Height(height).Render(left),
+		" ",
+		styles[1].Height(height).Render(right),
+	)
+}
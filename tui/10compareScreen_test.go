@@ -0,0 +1,89 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestSideBySideLayout(t *testing.T) {
+	cat := "```\n/\\_/\\\n( o.o )\n > ^ <\n```"
+	got := ansi.Strip(sideBySide("1/2 cat", cat, "2/2 a long dog name", "```\no\n```", 0))
+
+	// Each frame is as wide as its widest line and as tall as the taller
+	// one, with two columns and two rows of border and padding around it
+	frame := previewStyle.GetHorizontalFrameSize()
+	wantWidth := (7 + frame) + 1 + (19 + frame)
+	wantHeight := 4 + previewStyle.GetVerticalFrameSize()
+	if w, h := lipgloss.Width(got), lipgloss.Height(got); w != wantWidth || h != wantHeight {
+		t.Fatalf("sideBySide() is %dx%d, want %dx%d:\n%s", w, h, wantWidth, wantHeight, got)
+	}
+	lines := strings.Split(got, "\n")
+	for i, line := range lines {
+		if w := lipgloss.Width(line); w != wantWidth {
+			t.Errorf("line %d is %d wide, want %d:\n%s", i, w, wantWidth, got)
+		}
+	}
+	// Aligned at the top, with the shorter frame padded to end level
+	if !strings.Contains(lines[1], "1/2 cat") || !strings.Contains(lines[1], "2/2 a long dog name") {
+		t.Errorf("labels aren't on the same row:\n%s", got)
+	}
+	if !strings.Contains(lines[2], "/\\_/\\") || !strings.Contains(lines[2], " o ") {
+		t.Errorf("the art doesn't start on the same row:\n%s", got)
+	}
+	if strings.Count(lines[0], "╭") != 2 || strings.Count(lines[len(lines)-1], "╰") != 2 {
+		t.Errorf("the frames don't start and end on the same rows:\n%s", got)
+	}
+	if strings.Contains(got, "```") {
+		t.Errorf("fences shown:\n%s", got)
+	}
+}
+
+func TestCompareKeys(t *testing.T) {
+	m := NewCompareModel([]string{"a", "b", "c"}, []string{"A", "B", "C"})
+	if m.left != 1 || m.right != 2 || m.active != 1 {
+		t.Fatalf("left %d, right %d, active %d, want the last two with the right one active", m.left, m.right, m.active)
+	}
+	press := func(keys ...string) {
+		for _, k := range keys {
+			next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+			m = next.(compareModel)
+		}
+	}
+	// Down from the last art wraps around to the first
+	press("j")
+	if m.right != 0 || m.left != 1 {
+		t.Errorf("left %d, right %d, want the right side wrapped to the first", m.left, m.right)
+	}
+	press("h", "k", "k")
+	if m.active != 0 || m.left != 2 || m.right != 0 {
+		t.Errorf("active %d, left %d, right %d, want the left side stepped back twice", m.active, m.left, m.right)
+	}
+	view := ansi.Strip(m.View())
+	if !strings.Contains(view, "3/3 c") || !strings.Contains(view, "1/3 a") {
+		t.Errorf("View() doesn't label the arts picked:\n%s", view)
+	}
+}
+
+func TestCompareFromChat(t *testing.T) {
+	m := newTestChat(t, answering("unused"))
+	m.arts = []string{"```\nfirst\n```", "```\nsecond\n```"}
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g"), Alt: true})
+	compare, ok := next.(compareModel)
+	if !ok {
+		t.Fatalf("alt+g went to %T, want the compare screen", next)
+	}
+	if view := ansi.Strip(compare.View()); !strings.Contains(view, "1/2 Art 1") || !strings.Contains(view, "second") {
+		t.Errorf("View() doesn't show the session's art:\n%s", view)
+	}
+	back, _ := compare.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if _, ok := back.(chatModel); !ok {
+		t.Errorf("esc went to %T, want the chat", back)
+	}
+}
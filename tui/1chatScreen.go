@@ -4,43 +4,147 @@ Copyright © 2024 Eric Culley <https://github.com/ericulley>
 package tui
 
 import (
-	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/sashabaranov/go-openai"
 )
 
+var statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+
+var bannerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Bold(true)
+
+var previewStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+
 type chatModel struct {
 	textarea    textarea.Model
 	viewport    viewport.Model
 	messages    []string
 	senderStyle lipgloss.Style
 	err         error
-	aiClient    *openai.Client
+	aiClient    ChatClient
 	ascii       *ascii
+	history     []openai.ChatCompletionMessage
+	truncated   bool
+	partial     string
+	seed        *int
+	status      string
+	arts        []string
+	artIndex    int
+	previewing  bool
+	prompts     promptHistory
+	padding     int
+	width       int
+	height      int
+	exampleMode bool
+	branches    []branch
+	branchIndex int
+	welcome     string
+	expanded    bool
+	sessionCost float64
+	minimal     bool
+	model       string
+	lastPrompt  string
+	// Length of the history before the last prompt was added
+	lastPromptAt int
+	// A crashed session's conversation, until it's restored or discarded
+	recovered []turn
+	// Set while asking about existing art, so the answer isn't taken as new art
+	explaining bool
+	// Lost the connection, example art stands in until it's back
+	offline bool
+	// The kind of art the model is asked for
+	style string
+	// The role the next message is added with, user unless composing
+	role string
+	// Whether the arrow keys scroll the conversation instead of the message box
+	viewportFocused bool
+	// Keep the current art above the conversation while scrolling
+	pinned bool
+	// Colors picked to show art in for the rest of the session
+	artForeground string
+	artBackground string
+	// Whether a request is in flight, and the id of the latest one
+	waiting  bool
+	requests int
+	// Which thinking message is showing while waiting
+	thinkingIndex int
+	// The id the conversation is saved under, so it can be resumed
+	conversation string
+	// Whether the message box takes a path to art to improve instead of a
+	// prompt, and the art being improved until the answer arrives
+	improving bool
+	original  string
+	// When a request held back by the rate limit will go out
+	limitedUntil time.Time
+	// Where dividers were put in, as positions in the history
+	dividers []int
+	// Counts resizes, so only the last of a burst is laid out
+	resizes int
+	// Counts autosave timers started, so only the latest one keeps going
+	autosaves int
+	// The transcript as last formatted, shared by copies of the chat
+	formatted *transcriptCache
+	// Whether new art is copied to the clipboard as it arrives
+	autoCopy bool
+	// The position in the history of the message picked to copy, or -1
+	selected int
+	// Art shown at the top of the conversation
+	greeting string
+	// Whether art is captioned with the prompt it was made from
+	captions bool
+	// The response being streamed in at a readable pace, and the finished
+	// response waiting for it to catch up
+	stream *streamBuffer
+	held   *responseMsg
 }
 
 type ascii struct {
-	art string
+	art  string
+	seed *int
+	// Each piece of art when a response has more than one
+	variants []string
+	// Shown with the art, like whether it was copied
+	notice string
 }
 
 type asciiMsg bool
 
+// promptLimit is the most characters a message can be, counted by rune so
+// every character typed counts as one whatever its width.
+const promptLimit = 280
+
+// continuePrompt is sent when the user asks to finish a truncated response.
+const continuePrompt = "Your last response was cut off. Continue exactly where you left off, without repeating anything."
+
+// explainPrompt asks the model about a piece of art it made earlier in the
+// conversation. The art is quoted so it's clear which one is meant.
+func explainPrompt(art string) string {
+	return "Give this ascii art you made earlier a short title and explain what it shows. Don't draw any new art.\n" + fence + "\n" + stripFence(art) + "\n" + fence
+}
+
 func NewChatModel() chatModel {
 	ta := textarea.New()
-	ta.Placeholder = "Send a message...(esc to exit)"
 	ta.Focus()
 
 	ta.Prompt = "> "
-	ta.CharLimit = 280
+	// The textarea measures its limit in display width but cuts pastes by
+	// rune, so emoji can overshoot it. The chat enforces promptLimit by rune
+	// instead.
+	ta.CharLimit = 0
 
 	ta.SetWidth(80)
 	ta.SetHeight(1)
@@ -50,41 +154,260 @@ func NewChatModel() chatModel {
 
 	ta.ShowLineNumbers = false
 
-	vp := viewport.New(30, 10)
-	vp.SetContent(`Ask ChatGPT to create some ascii art!
-Type a message and press Enter to send.`)
+	welcome := welcomeMessage()
+	greeting := greetingArt()
+	start := withGreeting(greeting, welcome)
+	vp := viewport.New(max(30, lipgloss.Width(start)), max(10, lipgloss.Height(start)))
+	vp.SetContent(start)
 
 	ta.KeyMap.InsertNewline.SetEnabled(false)
 
-	return chatModel{
-		textarea:    ta,
-		messages:    []string{},
-		viewport:    vp,
-		senderStyle: lipgloss.NewStyle().Foreground(lipgloss.Color("5")),
-		err:         nil,
-		aiClient:    openai.NewClient(os.Getenv("OPENAI_API_KEY")),
-		ascii:       nil,
+	m := chatModel{
+		textarea:        ta,
+		messages:        []string{},
+		viewport:        vp,
+		senderStyle:     lipgloss.NewStyle().Foreground(lipgloss.Color("5")),
+		err:             nil,
+		aiClient:        NewChatClient(),
+		ascii:           nil,
+		history:         []openai.ChatCompletionMessage{},
+		truncated:       false,
+		partial:         "",
+		seed:            envSeed(),
+		status:          "",
+		arts:            []string{},
+		artIndex:        0,
+		previewing:      false,
+		prompts:         newPromptHistory(os.Getenv("ASCII_HISTORY_FILE")),
+		padding:         max(0, envInt("ASCII_PADDING", 1)),
+		width:           0,
+		height:          0,
+		exampleMode:     len(apiKeys()) == 0,
+		branches:        []branch{},
+		branchIndex:     0,
+		welcome:         welcome,
+		expanded:        false,
+		sessionCost:     0,
+		minimal:         envBool("ASCII_MINIMAL", false),
+		model:           models()[0],
+		lastPrompt:      "",
+		lastPromptAt:    0,
+		recovered:       nil,
+		explaining:      false,
+		offline:         false,
+		style:           envStyle(),
+		role:            openai.ChatMessageRoleUser,
+		viewportFocused: false,
+		pinned:          false,
+		artForeground:   "",
+		artBackground:   "",
+		waiting:         false,
+		requests:        0,
+		thinkingIndex:   0,
+		conversation:    newConversationID(),
+		improving:       false,
+		original:        "",
+		limitedUntil:    time.Time{},
+		dividers:        []int{},
+		resizes:         0,
+		autosaves:       0,
+		formatted:       &transcriptCache{},
+		autoCopy:        envBool("ASCII_AUTO_COPY", false),
+		selected:        -1,
+		greeting:        greeting,
+		captions:        envBool("ASCII_CAPTIONS", false),
+		stream:          nil,
+		held:            nil,
 	}
+	if turns, ok := loadRecovery(); ok {
+		m.recovered = turns
+		m.welcome = "The last session didn't exit cleanly. Press ctrl+r to restore it, or send a message to start over.\n\n" + m.welcome
+		m.viewport.SetContent(m.transcript())
+	}
+	return m
+}
+
+// reset returns a new conversation that keeps this session's prompt history,
+// cost and size.
+func (m chatModel) reset() chatModel {
+	reset := NewChatModel()
+	// The snapshot found is this session's own, not a crashed one
+	reset.recovered = nil
+	reset.welcome = welcomeMessage()
+	reset.viewport.SetContent(reset.transcript())
+	reset.prompts = m.prompts
+	reset.sessionCost = m.sessionCost
+	reset.viewport.Width = m.viewport.Width
+	reset.textarea.SetWidth(m.viewport.Width)
+	// Request ids carry on, so an answer still on its way to the old
+	// conversation can't pass for one to the new one
+	reset.requests = m.requests
+	// The running autosave timer carries on too
+	reset.autosaves = m.autosaves
+	return reset
 }
 
 func (m chatModel) Init() tea.Cmd {
-	return textarea.Blink
+	return tea.Batch(textarea.Blink, autosaveTick(m.autosaves))
 }
 
 func (m chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case artDrawnMsg:
+		return m, tea.Tick(artFrames, func(time.Time) tea.Msg { return asciiMsg(true) })
 	case asciiMsg:
-		return NewQuestionModel(m.ascii.art).Update(msg)
+		if m.ascii == nil {
+			// The art was dropped before the message arrived, e.g. by ctrl+l
+			debugf("ignoring art message with no art stored")
+			return m, nil
+		}
+		if len(m.ascii.variants) > 1 {
+			sheet := NewSheetModel(m.ascii.variants, m.ascii.seed)
+			sheet.chat = &m
+			sheet.width = m.width
+			return sheet, nil
+		}
+		question := NewQuestionModel(m.artRecord(m.ascii.art, m.ascii.seed))
+		// Keep the session around in case the user wants to keep chatting
+		question.chat = &m
+		question.notice = m.ascii.notice
+		if m.width > 0 {
+			question.width = m.width
+			question.height = m.height
+		}
+		return question.Update(msg)
+	case responseMsg:
+		// Answers to requests from before a reset are dropped
+		if !m.waiting || msg.id != m.requests {
+			return m, nil
+		}
+		if msg.err == nil && m.stream != nil && !m.stream.caughtUp() {
+			// Finish showing the streamed text first
+			m.held = &msg
+			return m, nil
+		}
+		m.stream = nil
+		return m, m.receive(msg)
+	case streamMsg:
+		if !m.waiting || int(msg) != m.requests || m.stream == nil {
+			return m, nil
+		}
+		_, step := streamStep()
+		m.stream.release(step)
+		if m.held != nil && m.stream.caughtUp() {
+			held := *m.held
+			m.stream, m.held = nil, nil
+			return m, m.receive(held)
+		}
+		m.refresh()
+		return m, streamTick(int(msg))
+	case transcriptCopiedMsg:
+		m.status = msg.status()
+		return m, nil
+	case configEditedMsg:
+		m.configEdited(msg)
+		return m, nil
+	case summaryMsg:
+		if !m.waiting || msg.id != m.requests {
+			return m, nil
+		}
+		m.showSummary(msg)
+		return m, nil
+	case thinkingMsg:
+		if !m.waiting || int(msg) != m.requests {
+			return m, nil
+		}
+		m.thinkingIndex++
+		return m, thinkingTick(int(msg))
+	case reconnectMsg:
+		if !msg {
+			return m, reconnectTick()
+		}
+		m.offline = false
+		m.messages = append(m.messages, statusStyle.Render("Reconnected to OpenAI, back to real generation."))
+		m.refresh()
+		return m, nil
+	case autosaveMsg:
+		if msg.id != m.autosaves {
+			// A timer that's been replaced by a newer one
+			return m, nil
+		}
+		if len(m.history) > 0 {
+			saveRecovery(m.history)
+			saveConversation(m.conversation, m.history)
+		}
+		return m, autosaveTick(m.autosaves)
 	case tea.WindowSizeMsg:
-		m.viewport.Width = msg.Width
-		m.textarea.SetWidth(msg.Width)
+		// The first size is laid out right away so the chat starts out right
+		if m.width > 0 && resizeDebounce() > 0 {
+			m.resizes++
+			return m, debounceResize(m.resizes, msg)
+		}
+		m.resize(msg)
+		return m, nil
+	case resizeMsg:
+		if msg.id == m.resizes {
+			m.resize(msg.size)
+		}
 		return m, nil
 	case tea.KeyMsg:
+		if m.waiting && slices.Contains([]string{"enter", "alt+z", "ctrl+g", "alt+x", "alt+r", "ctrl+r", "ctrl+o", "alt+o", "alt+h", "alt+w", "alt+p"}, msg.String()) {
+			// One request at a time, and the conversation stays put until
+			// its answer is in
+			return m, nil
+		}
+		if m.viewportFocused {
+			// Movement keys belong to the conversation while it has focus
+			switch msg.String() {
+			case "tab", "esc":
+				m.viewportFocused = false
+				m.selected = -1
+				return m, m.textarea.Focus()
+			case "up", "k":
+				m.viewport.LineUp(1)
+				return m, nil
+			case "down", "j":
+				m.viewport.LineDown(1)
+				return m, nil
+			case "home", "g":
+				m.viewport.GotoTop()
+				return m, nil
+			case "end", "G":
+				m.viewport.GotoBottom()
+				return m, nil
+			case "[", "]":
+				// Pick out a message to copy
+				step := 1
+				if msg.String() == "[" {
+					step = -1
+				}
+				m.selectMessage(step)
+				return m, nil
+			case "y":
+				m.copySelected()
+				return m, nil
+			}
+		}
 		switch msg.String() {
+		case "tab":
+			// Move focus to the conversation to scroll it with the arrows
+			m.viewportFocused = true
+			m.textarea.Blur()
+			return m, nil
 		case "esc", "ctrl+c":
 			// Quit.
 			fmt.Println(m.textarea.Value())
+			if err := saveConversation(m.conversation, m.history); err != nil {
+				debugf("saving conversation %s: %v", m.conversation, err)
+			}
 			return m, tea.Quit
+		case "alt+z":
+			// Surprise me: send a made up prompt in place of whatever's typed
+			if m.improving || m.role != openai.ChatMessageRoleUser {
+				return m, nil
+			}
+			m.textarea.SetValue(surprisePrompt())
+			fallthrough
 		case "enter":
 			v := m.textarea.Value()
 
@@ -92,44 +415,273 @@ func (m chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Don't send empty messages.
 				return m, nil
 			}
-
-			// Simulate sending a message. In your application you'll want to
-			// also return a custom command to send the message off to
-			// a server.
-			// Send message to openai
-			resp, err := m.SendMessage(v)
-			if err != nil {
-				fmt.Printf("Completion error: %v\n", err)
+			if m.role != openai.ChatMessageRoleUser {
+				// Composed messages go into the history without being sent,
+				// system ones before what was said so far
+				if at := insertedAt(m.history, m.role); at < len(m.history) {
+					m.shiftPositions(at, 1)
+				}
+				m.history = insertMessage(m.history, m.role, v)
+				m.messages = append(m.messages, m.senderStyle.Render("("+m.role+"): ")+v)
+				m.textarea.Reset()
+				m.role = openai.ChatMessageRoleUser
+				m.refresh()
+				return m, nil
 			}
-			respContent := resp.Message.Content
 
-			m.messages = append(m.messages, m.senderStyle.Render("You: ")+v)
-			m.viewport.SetContent(strings.Join(m.messages, "\n"))
-			m.messages = append(m.messages, m.senderStyle.Render("ChatGPT: "+respContent))
-			m.viewport.SetContent(strings.Join(m.messages, "\n"))
 			m.textarea.Reset()
+			// Sending a message always brings the conversation back down
 			m.viewport.GotoBottom()
-
-			// Check for ascii art code snippet and prompt to save it
-			hasCodeSnippet := strings.Contains(respContent, "```")
-			if hasCodeSnippet {
-				start := strings.Index(respContent, "```")
-				end := strings.LastIndex(respContent, "```") + 3
-				m.ascii = &ascii{art: respContent[start:end]}
-				return m, storedAsciiArt
+			// A new prompt abandons any truncated response
+			m.truncated = false
+			m.partial = ""
+			// Starting over instead of restoring the crashed session
+			m.recovered = nil
+			if m.improving {
+				// The message is a path to art to improve
+				return m, m.improveArt(v)
 			}
-
+			m.original = ""
+			m.messages = append(m.messages, m.senderStyle.Render(userLabel()+": ")+v)
+			m.prompts.add(v)
+			m.previewing = false
+			m.lastPrompt = v
+			m.lastPromptAt = len(m.history)
+			return m, m.send(v)
+		case "ctrl+g":
+			// Ask the model to pick up where a truncated response stopped
+			if !m.truncated {
+				return m, nil
+			}
+			m.messages = append(m.messages, m.senderStyle.Render(userLabel()+": ")+"(continue)")
+			return m, m.send(continuePrompt)
+		case "alt+i":
+			// Switch between asking for new art and improving art from a file
+			m.improving = !m.improving
+			return m, nil
+		case "alt+a":
+			// Pick the role of the next message, for prompt engineering
+			if envBool("ASCII_ROLE_COMPOSER", false) {
+				m.role = nextRole(m.role)
+			}
+			return m, nil
+		case "alt+p":
+			// Build the prompt from a form instead
+			form := NewFormModel()
+			form.chat = &m
+			return form, textinput.Blink
+		case "alt+t":
+			// Pin the current art to the top of the chat
+			m.pinned = !m.pinned
+			m.layout()
+			return m, nil
+		case "alt+s":
+			// Ask for a different kind of art from the next message on
+			m.style = nextStyle(m.style)
+			m.messages = append(m.messages, statusStyle.Render("Art style: "+m.style))
+			m.refresh()
+			return m, nil
+		case "alt+x":
+			// Ask about the art being previewed, or the latest one
+			if len(m.arts) == 0 {
+				return m, nil
+			}
+			m.messages = append(m.messages, m.senderStyle.Render(userLabel()+": ")+"(explain this art)")
+			m.truncated = false
+			m.partial = ""
+			m.explaining = true
+			cmd := m.send(explainPrompt(m.arts[m.artIndex]))
+			m.explaining = false
+			return m, cmd
+		case "ctrl+r":
+			// Restore the conversation from a crashed session
+			if m.recovered != nil {
+				m.restoreTurns(m.recovered)
+			}
+			return m, nil
+		case "alt+r":
+			// Try the last prompt again with the next model, leaving the earlier
+			// answer in the transcript to compare against
+			if m.lastPrompt == "" {
+				return m, nil
+			}
+			list := models()
+			m.model = list[(slices.Index(list, m.model)+1)%len(list)]
+			m.history = m.history[:min(m.lastPromptAt, len(m.history))]
+			m.truncated = false
+			m.partial = ""
+			m.messages = append(m.messages, m.senderStyle.Render(userLabel()+": ")+m.lastPrompt+statusStyle.Render(" (resent to "+m.model+")"))
+			return m, m.send(m.lastPrompt)
+		case "ctrl+y":
+			// Paste art from the clipboard for the model to work from
+			m.pasteArt(clipboard.ReadAll())
+			return m, nil
+		case "ctrl+n", "ctrl+p":
+			// Flip through the art generated this session
+			if len(m.arts) == 0 {
+				return m, nil
+			}
+			if m.previewing && msg.String() == "ctrl+n" {
+				m.artIndex = (m.artIndex + 1) % len(m.arts)
+			} else if m.previewing {
+				m.artIndex = (m.artIndex - 1 + len(m.arts)) % len(m.arts)
+			}
+			m.previewing = true
+			m.layout()
+			return m, nil
+		case "ctrl+o":
+			// Branch off to try a different direction
+			m.branchOff()
+			return m, nil
+		case "alt+o":
+			m.switchBranch()
+			return m, nil
+		case "alt+-":
+			// Mark the start of a new topic, without sending anything
+			m.messages = append(m.messages, dividerMessage)
+			m.dividers = append(m.dividers, len(m.history))
+			m.refresh()
+			return m, nil
+		case "ctrl+s":
+			// Save the conversation so it can be replayed later
+			if path, err := saveTranscript(m.history, m.dividers); err != nil {
+				m.status = fmt.Sprintf("Could not save transcript: %v", err)
+			} else {
+				m.status = "Saved transcript to " + path
+			}
+			return m, nil
+		case "alt+k":
+			// Copy the conversation as markdown, for pasting elsewhere
+			if len(m.history) == 0 {
+				return m, nil
+			}
+			m.status = "Copying the transcript..."
+			return m, copyTranscript(m.history, m.dividers)
+		case "alt+w":
+			// Wrap up with a summary of the session, kept out of the history
+			if len(m.history) == 0 {
+				return m, nil
+			}
+			m.messages = append(m.messages, m.senderStyle.Render(userLabel()+": ")+"(summarize the session)")
+			m.refresh()
+			return m, m.summarize()
+		case "alt+g":
+			// Compare two pieces of art from the session side by side
+			if len(m.arts) < 2 {
+				return m, nil
+			}
+			titles := make([]string, len(m.arts))
+			for i := range m.arts {
+				titles[i] = fmt.Sprintf("Art %d", i+1)
+			}
+			compare := NewCompareModel(titles, m.arts)
+			compare.chat = &m
+			compare.width, compare.height = m.width, m.height
+			return compare, nil
+		case "alt+c":
+			// Edit the settings, suspending the chat until the editor exits
+			if _, err := os.Stat(configFile); err != nil {
+				m.messages = append(m.messages, statusStyle.Render("There's no "+configFile+" file to edit yet, run make env to create one."))
+				m.refresh()
+				return m, nil
+			}
+			return m, editConfig()
+		case "alt+n":
+			// Show or hide the prompt under each piece of art
+			m.captions = !m.captions
+			m.refresh()
 			return m, nil
-		case tea.KeyUp.String():
-			m.viewport.LineUp(1)
+		case "alt+y":
+			// Turn copying new art as it arrives on or off
+			m.autoCopy = !m.autoCopy
+			if m.autoCopy {
+				m.status = "New art will be copied to the clipboard"
+			} else {
+				m.status = "New art won't be copied to the clipboard"
+			}
+			return m, nil
+		case "alt+v":
+			// Animate how the art changed over the session
+			if len(m.arts) == 0 {
+				m.status = "No art yet to animate"
+			} else if path, err := saveAnimation(m.arts); err != nil {
+				m.status = fmt.Sprintf("Could not save the animation: %v", err)
+			} else {
+				m.status = fmt.Sprintf("Saved %d frames to %s", len(m.arts), path)
+			}
+			return m, nil
+		case "alt+m":
+			// Hide or show everything but the conversation
+			m.minimal = !m.minimal
 			return m, nil
-		case tea.KeyDown.String():
-			m.viewport.LineDown(1)
+		case "alt+e":
+			// Show truncated lines in full
+			m.expanded = !m.expanded
+			m.refresh()
+			return m, nil
+		case "ctrl+l":
+			// Start the conversation over, dropping the answer to any request
+			// still in flight
+			saveConversation(m.conversation, m.history)
+			return m.reset(), nil
+		case "alt+h":
+			// Pick an earlier conversation to carry on with
+			saveConversation(m.conversation, m.history)
+			conversations, err := listConversations()
+			if err != nil {
+				m.status = "Could not list conversations: " + err.Error()
+				return m, nil
+			}
+			picker := NewResumeModel(conversations)
+			picker.chat = &m
+			return picker, nil
+		case tea.KeyUp.String(), tea.KeyDown.String():
+			// Recall earlier prompts when there isn't a prompt being typed.
+			// Scrolling with the arrows needs the conversation focused
+			if len(m.prompts.prompts) > 0 && (m.textarea.Value() == "" || m.prompts.navigating()) {
+				if msg.Type == tea.KeyUp {
+					m.textarea.SetValue(m.prompts.prev())
+				} else {
+					m.textarea.SetValue(m.prompts.next())
+				}
+			}
+			return m, nil
+		case tea.KeyPgUp.String():
+			m.viewport.HalfViewUp()
+			return m, nil
+		case tea.KeyPgDown.String():
+			m.viewport.HalfViewDown()
 			return m, nil
 		default:
+			if m.viewportFocused {
+				// Nothing to type into
+				return m, nil
+			}
+			if i, ok := insertKey(msg.String()); ok {
+				// Type a configured snippet in at the cursor, if it fits
+				tokens := insertTokens()
+				if i < len(tokens) {
+					token := expandToken(tokens[i], time.Now())
+					if utf8.RuneCountInString(m.textarea.Value()+token) <= promptLimit {
+						m.textarea.InsertString(token)
+					}
+				}
+				return m, nil
+			}
+			// Already full, ignore keys that type like the textarea would. A
+			// copy of the textarea shares its lines, so it can't be put back
+			// after the fact
+			typing := msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace || key.Matches(msg, m.textarea.KeyMap.InsertNewline)
+			if typing && utf8.RuneCountInString(m.textarea.Value()) >= promptLimit {
+				return m, nil
+			}
 			// Send all other keypresses to the textarea.
 			var cmd tea.Cmd
 			m.textarea, cmd = m.textarea.Update(msg)
+			if v := []rune(m.textarea.Value()); len(v) > promptLimit {
+				// Keep as much of a paste as fits
+				m.textarea.SetValue(string(v[:promptLimit]))
+			}
 			return m, cmd
 		}
 
@@ -152,52 +704,461 @@ func (m chatModel) View() string {
 	// 	}
 	// 	return fmt.Sprintln("")
 	// } else {
-	return fmt.Sprintf(
-		"%s\n\n%s",
+	if small, ok := tooSmall(m.width, m.height); ok {
+		return small
+	}
+	// The placeholder only shows while the box is empty
+	m.textarea.Placeholder = m.hint()
+	preview := ""
+	if m.previewing && len(m.arts) > 0 {
+		preview = fmt.Sprintf("Art %d/%d\n", m.artIndex+1, len(m.arts)) + stripFence(m.arts[m.artIndex])
+		if !m.minimal {
+			preview = previewStyle.Render(preview)
+		}
+		preview += "\n\n"
+	}
+	// Minimal mode only renders the conversation and the message box
+	if m.minimal {
+		return fmt.Sprintf("%s\n\n%s%s", m.viewport.View(), preview, m.textarea.View()) + "\n\n"
+	}
+	banner := ""
+	if header := m.pinnedArt(); header != "" {
+		banner = header + "\n"
+	}
+	if m.exampleMode {
+		banner += bannerStyle.Render("example mode — set OPENAI_API_KEY for real generation") + "\n\n"
+	} else if m.offline {
+		banner += bannerStyle.Render("offline — showing example art until the connection is back") + "\n\n"
+	}
+	view := banner + fmt.Sprintf(
+		"%s\n\n%s%s\n%s",
 		m.viewport.View(),
+		preview,
 		m.textarea.View(),
+		m.focusLine(),
 	) + "\n\n"
+	if m.status != "" {
+		view += statusStyle.Render(m.status) + "\n"
+	}
+	return lipgloss.NewStyle().Padding(0, m.padding).Render(view)
 	// }
 }
 
-func (m chatModel) SendMessage(content string) (*openai.ChatCompletionChoice, error) {
-	// If there is no openai api key, return example art
-	if os.Getenv("OPENAI_API_KEY") == "" {
-		fmt.Println("No openai api key found. Using example art.")
-		choice := &openai.ChatCompletionChoice{
-			Index: 0,
-			Message: openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: "```\n    _____\\    _______\n   /      \\  |      /\\\n  /_______/  |_____/  \\\n |   \\   /        /   /\n  \\   \\ MISSING \\/   /\n   \\  /   API    \\__/_\n    \\/ ___KEY_ /\\\n      /  \\    /  \\\n     /\\   \\  /   /\n       \\   \\/   /\n        \\___\\__/\n```",
-			},
-			FinishReason: "stop",
-		}
-		return choice, nil
-	}
-	// Otherwise send the message to openai
-	var maxTokens int
-	if os.Getenv("OPENAI_MAX_TOKENS") != "" {
-		maxTokens, _ = strconv.Atoi(os.Getenv("OPENAI_MAX_TOKENS"))
+// chatChrome is the number of lines in the chat that aren't the conversation,
+// like the message box and status line.
+const chatChrome = 8
+
+// pinnedArt renders the current art for the top of the chat when it's pinned
+// and there's room for it along with a few lines of conversation.
+func (m chatModel) pinnedArt() string {
+	if !m.pinned || len(m.arts) == 0 {
+		return ""
+	}
+	header := previewStyle.Render(stripFence(m.arts[m.artIndex]))
+	if m.height > 0 && lipgloss.Height(header)+3 > m.height-chatChrome {
+		// Not enough room, the conversation gets the whole chat
+		return ""
+	}
+	return header
+}
+
+// resize lays the chat out for a new terminal size.
+func (m *chatModel) resize(size tea.WindowSizeMsg) {
+	m.width = size.Width
+	m.height = size.Height
+	// Leave room for the padding on either side
+	width := max(1, size.Width-2*m.padding)
+	m.viewport.Width = width
+	m.textarea.SetWidth(width)
+	m.layout()
+	// Long lines are fit to the new width
+	m.refresh()
+}
+
+// layout sizes the conversation to fit the greeting, shrinking it to make
+// room for pinned art and so it never pushes the message box off a short
+// terminal.
+func (m *chatModel) layout() {
+	height := max(10, lipgloss.Height(withGreeting(m.greeting, m.welcome)))
+	if m.height > 0 {
+		room := m.height - chatChrome
+		if header := m.pinnedArt(); header != "" {
+			room -= lipgloss.Height(header)
+		}
+		height = max(3, min(height, room))
+	}
+	m.viewport.Height = height
+}
+
+// focusLine goes under the message box, counting what's been typed, with the
+// scrolling keys while the conversation has focus, or showing that a response
+// is on its way.
+func (m chatModel) focusLine() string {
+	if m.waiting && time.Now().Before(m.limitedUntil) {
+		return statusStyle.Render("waiting to respect rate limit...")
+	}
+	if messages := thinkingMessages(); m.waiting && len(messages) > 0 {
+		return statusStyle.Render(messages[m.thinkingIndex%len(messages)])
+	}
+	if line := m.selectionLine(); m.viewportFocused && line != "" {
+		return statusStyle.Render(line)
+	}
+	if m.viewportFocused {
+		return statusStyle.Render("scrolling: ↑/↓ or j/k, pgup/pgdown, g/G • [/] to pick a message • tab to type")
+	}
+	return statusStyle.Render(fmt.Sprintf("%d/%d", utf8.RuneCountInString(m.textarea.Value()), promptLimit))
+}
+
+// hint is the placeholder for the message box. It names the keys that do
+// something useful right now, taken from the textarea's own bindings where
+// they come from there.
+func (m chatModel) hint() string {
+	hints := []string{"enter to send"}
+	if m.improving {
+		return "Path to the art file to improve... (enter to load it, alt+i for new art, esc to exit)"
+	}
+	if m.role != openai.ChatMessageRoleUser {
+		hints = []string{"enter to add as " + m.role, "alt+a to change role"}
+	}
+	if newline := m.textarea.KeyMap.InsertNewline; newline.Enabled() {
+		hints = append(hints, strings.Join(newline.Keys(), "/")+" for newline")
+	}
+	switch {
+	case m.recovered != nil:
+		hints = append(hints, "ctrl+r to restore")
+	case m.truncated:
+		hints = append(hints, "ctrl+g to continue")
+	}
+	hints = append(hints, "esc to exit")
+	return "Send a message... (" + strings.Join(hints, ", ") + ")"
+}
+
+// send adds content to the conversation history and sends it to openai. The
+// response comes back as a responseMsg.
+func (m *chatModel) send(content string) tea.Cmd {
+	m.history = append(m.history, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: content,
+	})
+	// Show the prompt while waiting on the answer
+	m.refresh()
+	return m.request(false)
+}
+
+// responseMsg is the outcome of a request made by the chat.
+type responseMsg struct {
+	id         int
+	resp       completion
+	err        error
+	explaining bool
+	trimmed    bool
+}
+
+// request sends the conversation so far in the background, so the chat keeps
+// drawing while it waits. trimmed marks a retry with a shortened history.
+func (m *chatModel) request(trimmed bool) tea.Cmd {
+	m.waiting = true
+	m.requests++
+	m.thinkingIndex = 0
+	m.limitedUntil = time.Now().Add(rateLimitDelay(m.aiClient))
+	m.stream, m.held = nil, nil
+	var reveal tea.Cmd
+	if throttled(m.aiClient) && !m.offline {
+		m.stream = &streamBuffer{}
+		reveal = streamTick(m.requests)
+	}
+	chat := *m
+	history := slices.Clone(m.history)
+	id, explaining := m.requests, m.explaining
+	return tea.Batch(func() tea.Msg {
+		resp, err := chat.SendMessage(history)
+		return responseMsg{id: id, resp: resp, err: err, explaining: explaining, trimmed: trimmed}
+	}, thinkingTick(id), reveal)
+}
+
+// receive records a response in the transcript. When continuing a truncated
+// response, the two parts are stitched together before looking for art.
+func (m *chatModel) receive(msg responseMsg) tea.Cmd {
+	m.waiting = false
+	resp, err := msg.resp, msg.err
+	if err != nil && contextTooLong(err) && !msg.trimmed && len(m.history) > 1 {
+		// Try once more with less of the conversation
+		before := len(m.history)
+		m.history = trimHistory(m.history)
+		m.shiftPositions(0, len(m.history)-before)
+		m.messages = append(m.messages, statusStyle.Render("The conversation got too long for the model, so its oldest messages were dropped and the message was sent again."))
+		m.explaining = msg.explaining
+		cmd := m.request(true)
+		m.explaining = false
+		return cmd
+	}
+	if err != nil && disconnected(err) {
+		// Keep the chat usable until the connection comes back
+		m.history = m.history[:len(m.history)-1]
+		m.offline = true
+		m.messages = append(m.messages, statusStyle.Render("Couldn't reach OpenAI, so that message wasn't sent. Switching to example art until the connection is back."))
+		m.refresh()
+		return reconnectTick()
+	}
+	if err != nil {
+		m.messages = append(m.messages, statusStyle.Render(fmt.Sprintf("Completion error: %v", err)))
+		m.refresh()
+		return nil
+	}
+	m.history = append(m.history, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleAssistant,
+		Content: resp.Text,
+	})
+	// Keep every answer, since the chat may be quit from another screen
+	if err := saveConversation(m.conversation, m.history); err != nil {
+		debugf("saving conversation %s: %v", m.conversation, err)
+	}
+	respContent := resp.Text
+	for _, warning := range resp.Warnings {
+		m.messages = append(m.messages, statusStyle.Render(warning))
+	}
+	m.status = "model: " + m.model + " • style: " + m.style + " • seed: " + formatSeed(m.seed)
+	if requestCost, ok := cost(resp); ok {
+		m.sessionCost += requestCost
+		m.status += fmt.Sprintf(" • cost: $%.4f (session $%.4f)", requestCost, m.sessionCost)
+	}
+
+	// Image responses are converted to art, everything else is shown as text
+	if art, ok := imageArt(respContent); ok {
+		respContent = art
+	}
+	label := assistantLabel() + ": "
+	if m.model != models()[0] {
+		// Note which model answered once it's been switched
+		label = assistantLabel() + " (" + m.model + "): "
+	}
+	m.messages = append(m.messages, m.senderStyle.Render(label+respContent))
+	if m.truncated {
+		respContent = stitchArt(m.partial, respContent)
+	}
+	m.truncated = resp.FinishReason == finishLength
+	if m.truncated {
+		m.partial = respContent
+		m.messages = append(m.messages, m.senderStyle.Render(fmt.Sprintf(
+			"Warning: the response was cut off at %d tokens. Increase OPENAI_MAX_TOKENS or press ctrl+g to continue.",
+			maxTokens(),
+		)))
+	}
+	m.refresh()
+
+	// Half-drawn art isn't worth saving, wait for the rest of it
+	if m.truncated {
+		return nil
+	}
+
+	// Explanations may quote the art, it's not new art to save
+	if msg.explaining {
+		return nil
+	}
+
+	// Show what changed when art from a file was improved
+	if art, ok := extractArt(respContent); ok && m.original != "" {
+		m.messages = append(m.messages, statusStyle.Render("Changes from your art:")+"\n"+artDiff(m.original, art))
+		m.original = ""
+		m.refresh()
+	}
+
+	// Check for ascii art code snippet and prompt to save it
+	trimBlank := envBool("ASCII_TRIM_BLANK_LINES", true)
+	if variants := extractArts(respContent); len(variants) > 1 {
+		if trimBlank {
+			for i, variant := range variants {
+				variants[i] = trimBlankLines(variant)
+			}
+		}
+		m.ascii = &ascii{art: variants[0], seed: m.seed, variants: variants, notice: m.autoCopyArt(variants[0])}
+		m.caption()
+		m.arts = append(m.arts, variants...)
+		m.artIndex = len(m.arts) - 1
+		return storedAsciiArt
+	}
+	if art, ok := extractArt(respContent); ok {
+		if trimBlank {
+			art = trimBlankLines(art)
+		}
+		m.ascii = &ascii{art: art, seed: m.seed, notice: m.autoCopyArt(art)}
+		m.caption()
+		m.arts = append(m.arts, m.ascii.art)
+		m.artIndex = len(m.arts) - 1
+		return storedAsciiArt
+	}
+	return nil
+}
+
+// caption notes the prompt the art that just arrived was made from, for
+// showing under it when captions are on.
+func (m *chatModel) caption() {
+	if prompt := lastPrompt(m.history); prompt != "" {
+		m.messages = append(m.messages, captionPrefix+prompt)
+		m.refresh()
+	}
+}
+
+// lastPrompt returns the most recent message sent by the user, empty when
+// there isn't one.
+func lastPrompt(history []openai.ChatCompletionMessage) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == openai.ChatMessageRoleUser {
+			return history[i].Content
+		}
+	}
+	return ""
+}
+
+// autoCopyArt copies new art to the clipboard when auto copy is on, and
+// returns a note saying how it went. A clipboard that can't be written to is
+// only reported, the chat carries on.
+func (m *chatModel) autoCopyArt(art string) string {
+	if !m.autoCopy {
+		return ""
+	}
+	if err := clipboard.WriteAll(copyText(art, false)); err != nil {
+		m.status = fmt.Sprintf("Couldn't copy the art: %v", err)
+	} else {
+		m.status = "Copied the new art to the clipboard"
+	}
+	return m.status
+}
+
+func (m chatModel) SendMessage(history []openai.ChatCompletionMessage) (completion, error) {
+	if m.offline {
+		return completion{Text: exampleResponse(), Model: m.model, FinishReason: finishStop}, nil
+	}
+	var onChunk func(string)
+	if m.stream != nil {
+		onChunk = m.stream.write
+	}
+	// Without an openai api key the client answers with example art
+	return completeTo(m.aiClient, newRequest(m.model, history, m.seed, m.style), onChunk)
+}
+
+// refresh renders the transcript into the viewport and scrolls to the latest
+// message. Like a chat app, it stays put while the user has scrolled up to
+// read earlier messages, unless ASCII_STICKY_SCROLL is turned off.
+func (m *chatModel) refresh() {
+	follow := m.viewport.AtBottom() || !envBool("ASCII_STICKY_SCROLL", true)
+	m.viewport.SetContent(m.transcript())
+	if follow {
+		m.viewport.GotoBottom()
+	}
+}
+
+// WithClient has the chat send its requests with client, e.g. a fake one that
+// answers without a network connection.
+func (m chatModel) WithClient(client ChatClient) chatModel {
+	m.aiClient = client
+	_, m.exampleMode = client.(exampleClient)
+	return m
+}
+
+// WithMinimal starts the chat in minimal mode, without the banner, status
+// line or padding. Useful for screenshots and recordings.
+func (m chatModel) WithMinimal() chatModel {
+	m.minimal = true
+	return m
+}
+
+// transcript returns the conversation as shown in the viewport, or the
+// welcome message before anything has been said, under the greeting.
+func (m chatModel) transcript() string {
+	if len(m.messages) == 0 {
+		return withGreeting(m.greeting, m.welcome)
+	}
+	truncate := os.Getenv("ASCII_LONG_LINES") == "truncate" && !m.expanded
+	// Prose is kept to a readable width, art can still use all of it
+	width := m.viewport.Width
+	if limit := envInt("ASCII_PROSE_WIDTH", 100); limit > 0 && limit < width {
+		width = limit
+	}
+	indent := 0
+	if envBool("ASCII_CENTER_PROSE", false) {
+		indent = (m.viewport.Width - width) / 2
+	}
+	messages := m.messages
+	if !m.captions {
+		messages = withoutCaptions(messages)
+	}
+	if m.stream != nil && m.stream.revealed() != "" {
+		messages = append(slices.Clip(messages), m.streaming())
+	}
+	if m.formatted == nil {
+		return withGreeting(m.greeting, formatTranscript(messages, width, indent, truncate))
+	}
+	return withGreeting(m.greeting, m.formatted.format(messages, width, indent, truncate))
+}
+
+// pasteArt adds art read from the clipboard to the history for the next
+// prompt to work from, without sending anything.
+func (m *chatModel) pasteArt(art string, err error) {
+	if err != nil || strings.TrimSpace(art) == "" {
+		m.messages = append(m.messages, m.senderStyle.Render("Nothing to paste from the clipboard"))
 	} else {
-		maxTokens = 100
-	}
-	ctx := context.Background()
-	req := openai.ChatCompletionRequest{
-		Model:     "gpt-4o-mini",
-		MaxTokens: maxTokens,
-		Messages: []openai.ChatCompletionMessage{{
-			Role:    openai.ChatMessageRoleUser,
-			Content: content,
-		}},
-	}
-	resp, err := m.aiClient.CreateChatCompletion(ctx, req)
+		m.history = withArtContext(m.history, art)
+		m.messages = append(m.messages, m.senderStyle.Render(userLabel()+" (pasted art):")+"\n"+art)
+	}
+	m.refresh()
+}
+
+// withArtContext adds existing art to the history as context for the next
+// prompt, fenced so the model treats it as art to edit.
+func withArtContext(history []openai.ChatCompletionMessage, art string) []openai.ChatCompletionMessage {
+	return append(history, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: "Here is some existing ascii art to use as context for my next message:\n" + fence + "\n" + strings.Trim(art, "\n") + "\n" + fence,
+	})
+}
+
+// welcomeMessage returns the text shown before the first message. ASCII_WELCOME
+// overrides it, and setting it to an empty value hides it.
+func welcomeMessage() string {
+	if welcome, ok := os.LookupEnv("ASCII_WELCOME"); ok {
+		return welcome
+	}
+	return "Ask " + assistantLabel() + " to create some ascii art!\nType a message and press Enter to send."
+}
+
+// envSeed returns the seed set by OPENAI_SEED, or nil to let openai pick one.
+func envSeed() *int {
+	v := os.Getenv("OPENAI_SEED")
+	if v == "" {
+		return nil
+	}
+	seed, err := strconv.Atoi(v)
 	if err != nil {
-		fmt.Printf("Completion error: %v\n", err)
-		return nil, err
+		return nil
 	}
-	return &resp.Choices[0], nil
+	return &seed
 }
 
+func formatSeed(seed *int) string {
+	if seed == nil {
+		return "random"
+	}
+	return strconv.Itoa(*seed)
+}
+
+// maxTokens returns the completion token limit set by OPENAI_MAX_TOKENS.
+func maxTokens() int {
+	return envInt("OPENAI_MAX_TOKENS", 100)
+}
+
+// artDrawnMsg follows an update that stored new art in m.ascii. Bubbletea
+// draws the model after every update, so by the time it arrives the chat has
+// been drawn with the response the art came in.
+type artDrawnMsg struct{}
+
+// artFrames is how long the chat stays up once the response has been drawn,
+// before moving on to the art. The renderer writes at most 60 frames a second,
+// so a couple of frames is enough for the response to reach the screen.
+const artFrames = 2 * time.Second / 60
+
+// storedAsciiArt tells the chat there's new art in m.ascii to move on to,
+// once the response with it has been drawn.
 func storedAsciiArt() tea.Msg {
-	return asciiMsg(true)
+	return artDrawnMsg{}
 }
@@ -0,0 +1,480 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestChatUpdateKeys(t *testing.T) {
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+	tests := []struct {
+		name    string
+		typed   string
+		prompts []string
+		waiting bool
+		key     tea.KeyMsg
+		// What's left in the message box, whether a request went out, and
+		// whether the chat quit
+		wantValue string
+		wantSent  bool
+		wantQuit  bool
+	}{
+		{name: "enter sends", typed: "a cat", key: enter, wantSent: true},
+		{name: "enter on empty", key: enter},
+		{name: "enter while waiting", typed: "a dog", waiting: true, key: enter, wantValue: "a dog"},
+		{name: "esc quits", typed: "a cat", key: tea.KeyMsg{Type: tea.KeyEsc}, wantValue: "a cat", wantQuit: true},
+		{name: "up recalls", prompts: []string{"first", "second"}, key: tea.KeyMsg{Type: tea.KeyUp}, wantValue: "second"},
+		{name: "up without history", key: tea.KeyMsg{Type: tea.KeyUp}},
+		{name: "up while typing", typed: "draft", prompts: []string{"first"}, key: tea.KeyMsg{Type: tea.KeyUp}, wantValue: "draft"},
+		{name: "down past the end", prompts: []string{"first"}, key: tea.KeyMsg{Type: tea.KeyDown}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := answering("ok")
+			m := newTestChat(t, client)
+			for _, p := range tt.prompts {
+				m.prompts.add(p)
+			}
+			m.textarea.SetValue(tt.typed)
+			m.waiting = tt.waiting
+			next, cmd := m.Update(tt.key)
+			got := next.(chatModel)
+			if v := got.textarea.Value(); v != tt.wantValue {
+				t.Errorf("message box = %q, want %q", v, tt.wantValue)
+			}
+			sent := got.waiting && !tt.waiting
+			if sent != tt.wantSent {
+				t.Errorf("sent = %v, want %v", sent, tt.wantSent)
+			}
+			if sent {
+				if n := len(got.history); n != 1 || got.history[0].Role != openai.ChatMessageRoleUser || got.history[0].Content != tt.typed {
+					t.Errorf("history = %+v, want the prompt", got.history)
+				}
+				awaitMsg[responseMsg](t, cmd)
+			}
+			quit := false
+			if cmd != nil && !sent {
+				_, quit = cmd().(tea.QuitMsg)
+			}
+			if quit != tt.wantQuit {
+				t.Errorf("quit = %v, want %v", quit, tt.wantQuit)
+			}
+		})
+	}
+}
+
+func TestChatResponse(t *testing.T) {
+	art := "```\n/\\_/\\\n( o.o )\n```"
+	m := newTestChat(t, answering("Here's a cat:\n"+art))
+	m.textarea.SetValue("a cat")
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	next, cmd = next.Update(awaitMsg[responseMsg](t, cmd))
+	got := next.(chatModel)
+	if got.waiting {
+		t.Errorf("still waiting after the response")
+	}
+	if len(got.history) != 2 || got.history[1].Role != openai.ChatMessageRoleAssistant {
+		t.Fatalf("history = %+v, want the prompt and the answer", got.history)
+	}
+	if got.ascii == nil || got.ascii.art != art {
+		t.Errorf("stored art = %+v, want %q", got.ascii, art)
+	}
+	if !strings.Contains(got.viewport.View(), "ChatGPT: Here's a cat:") {
+		t.Errorf("viewport doesn't show the answer:\n%s", got.viewport.View())
+	}
+	// Moving on to the new art once the response is drawn
+	next, cmd = next.Update(awaitMsg[artDrawnMsg](t, cmd))
+	awaitMsg[asciiMsg](t, cmd)
+}
+
+func TestChatResponseError(t *testing.T) {
+	m := newTestChat(t, failing(errors.New("bad request")))
+	m.textarea.SetValue("a cat")
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	next, cmd = next.Update(awaitMsg[responseMsg](t, cmd))
+	got := next.(chatModel)
+	if got.waiting || cmd != nil {
+		t.Errorf("waiting = %v, cmd = %v after an error", got.waiting, cmd)
+	}
+	if last := got.messages[len(got.messages)-1]; !strings.Contains(last, "Completion error: bad request") {
+		t.Errorf("last message = %q, want the error", last)
+	}
+}
+
+func TestChatTruncatedContinue(t *testing.T) {
+	ctrlG := tea.KeyMsg{Type: tea.KeyCtrlG}
+	client := answering("Here's a tent:\n```\n /\\")
+	client.resp.Choices[0].FinishReason = openai.FinishReasonLength
+	m := newTestChat(t, client)
+	if _, cmd := m.Update(ctrlG); cmd != nil {
+		t.Fatal("ctrl+g did something with nothing cut off")
+	}
+
+	m = sendThrough(t, m, "a tent")
+	if !m.truncated {
+		t.Fatal("truncated = false after a response cut off by length")
+	}
+	if last := m.messages[len(m.messages)-1]; !strings.Contains(last, "the response was cut off") || !strings.Contains(last, "ctrl+g to continue") {
+		t.Errorf("last message = %q, want the truncation warning", last)
+	}
+	if m.ascii != nil {
+		t.Errorf("art = %+v, want nothing kept from half the art", m.ascii)
+	}
+
+	client.resp.Choices[0].Message.Content = "/__\\\n```"
+	client.resp.Choices[0].FinishReason = openai.FinishReasonStop
+	next, cmd := m.Update(ctrlG)
+	for next.(chatModel).waiting {
+		next, cmd = next.Update(awaitMsg[responseMsg](t, cmd))
+	}
+	m = next.(chatModel)
+	sent := client.sent()
+	if len(sent) != 2 {
+		t.Fatalf("sent %d requests, want the continue request after the first", len(sent))
+	}
+	messages := sent[1].Messages
+	if last := messages[len(messages)-1]; last.Content != continuePrompt {
+		t.Errorf("continued with %q, want %q", last.Content, continuePrompt)
+	}
+	if len(messages) < 3 || messages[len(messages)-2].Role != openai.ChatMessageRoleAssistant {
+		t.Errorf("continued with %+v, want the cut off response sent back", messages)
+	}
+	if m.truncated {
+		t.Error("truncated = true after the response was finished")
+	}
+	if m.ascii == nil || !strings.Contains(m.ascii.art, "/__\\") || !strings.Contains(m.ascii.art, " /\\") {
+		t.Errorf("art = %+v, want both halves stitched together", m.ascii)
+	}
+}
+
+func TestWithArtContext(t *testing.T) {
+	history := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "a cat"}}
+	for _, art := range []string{"/\\_/\\\n( o.o )", "\n\n/\\_/\\\n( o.o )\n"} {
+		got := withArtContext(slices.Clone(history), art)
+		if len(got) != 2 || got[0].Content != "a cat" {
+			t.Fatalf("withArtContext() = %+v, want the art added after the history", got)
+		}
+		if got[1].Role != openai.ChatMessageRoleUser || !strings.HasSuffix(got[1].Content, ":\n```\n/\\_/\\\n( o.o )\n```") {
+			t.Errorf("withArtContext(%q) added %+v, want the art fenced in a user message", art, got[1])
+		}
+	}
+}
+
+func TestChatPasteArt(t *testing.T) {
+	art := "/\\_/\\\n( o.o )"
+	client := answering("ok")
+	m := newTestChat(t, client)
+	m.pasteArt("", errors.New("no clipboard"))
+	m.pasteArt(" \n", nil)
+	if len(m.history) != 0 || !strings.Contains(m.messages[len(m.messages)-1], "Nothing to paste") {
+		t.Fatalf("history = %+v with nothing to paste, want nothing added", m.history)
+	}
+
+	m.pasteArt(art, nil)
+	if m.waiting || len(client.sent()) != 0 {
+		t.Fatal("pasted art was sent as a prompt")
+	}
+	if len(m.history) != 1 || m.history[0].Role != openai.ChatMessageRoleUser || !strings.Contains(m.history[0].Content, "```\n"+art+"\n```") {
+		t.Fatalf("history = %+v, want the pasted art fenced", m.history)
+	}
+	m = sendThrough(t, m, "give it a hat")
+	messages := client.sent()[0].Messages
+	if n := len(messages); n < 2 || !strings.Contains(messages[n-2].Content, art) || messages[n-1].Content != "give it a hat" {
+		t.Errorf("sent %+v, want the pasted art before the prompt", messages)
+	}
+
+	// ctrl+y pastes whatever's on the clipboard, if there is one
+	if clipboard.WriteAll(art) == nil {
+		next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlY})
+		if m = next.(chatModel); cmd != nil || !strings.Contains(m.history[len(m.history)-1].Content, art) {
+			t.Errorf("history = %+v after ctrl+y, want the clipboard's art added", m.history)
+		}
+	}
+}
+
+func TestChatResetDropsPendingAnswer(t *testing.T) {
+	m := newTestChat(t, answering("old answer"))
+	m.textarea.SetValue("a cat")
+	next, staleCmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	m = next.(chatModel)
+	if m.waiting {
+		t.Fatalf("still waiting after ctrl+l")
+	}
+	m.aiClient = answering("new answer")
+	m.textarea.SetValue("a dog")
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	stale := awaitMsg[responseMsg](t, staleCmd)
+	next, _ = next.Update(stale)
+	if m := next.(chatModel); !m.waiting || len(m.history) != 1 {
+		t.Fatalf("the answer from before ctrl+l was taken, history = %+v", m.history)
+	}
+	next, _ = next.Update(awaitMsg[responseMsg](t, cmd))
+	m = next.(chatModel)
+	if len(m.history) != 2 || m.history[1].Content != "new answer" {
+		t.Errorf("history = %+v, want the new prompt and its answer", m.history)
+	}
+}
+
+func TestChatViewPinnedArtWithBanner(t *testing.T) {
+	tests := []struct {
+		name    string
+		example bool
+		offline bool
+		banner  string
+	}{
+		{name: "example mode", example: true, banner: "example mode"},
+		{name: "offline", offline: true, banner: "offline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestChat(t, answering("ok"))
+			m.exampleMode, m.offline = tt.example, tt.offline
+			m.arts = []string{"```\n<pinned>\n```"}
+			m.pinned = true
+			view := m.View()
+			if !strings.Contains(view, "<pinned>") || !strings.Contains(view, tt.banner) {
+				t.Errorf("view doesn't have both the pinned art and the %s banner:\n%s", tt.banner, view)
+			}
+		})
+	}
+}
+
+// benchmarkRefresh times refreshing a chat with a 4000 message history as
+// its last message changes, like it does while an answer streams in.
+func benchmarkRefresh(b *testing.B, cached bool) {
+	testEnv(b)
+	b.Setenv("ASCII_GREETING", "")
+	m := NewChatModel()
+	m.resize(tea.WindowSizeMsg{Width: 100, Height: 40})
+	if !cached {
+		m.formatted = nil
+	}
+	for i := 0; i < 4000; i++ {
+		m.messages = append(m.messages, m.senderStyle.Render("You: ")+strings.Repeat("a long prompt that has to be wrapped ", 5))
+	}
+	m.refresh()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.messages[len(m.messages)-1] = strings.Repeat("x", i%200)
+		m.refresh()
+	}
+}
+
+func BenchmarkRefresh(b *testing.B) { benchmarkRefresh(b, true) }
+
+func BenchmarkRefreshUncached(b *testing.B) { benchmarkRefresh(b, false) }
+
+func TestChatArtMessageWithoutArt(t *testing.T) {
+	m := newTestChat(t, answering("ok"))
+	m.messages = append(m.messages, "You: a cat")
+	m.refresh()
+	before := m.View()
+	for _, msg := range []tea.Msg{artDrawnMsg{}, asciiMsg(true)} {
+		next, cmd := m.Update(msg)
+		got, ok := next.(chatModel)
+		if !ok {
+			t.Fatalf("Update(%T) with no art moved on to %T", msg, next)
+		}
+		if _, ok := msg.(asciiMsg); ok && cmd != nil {
+			t.Errorf("Update(asciiMsg) with no art returned a command")
+		}
+		if got.View() != before || len(got.messages) != 1 || got.ascii != nil {
+			t.Errorf("Update(%T) with no art changed the chat", msg)
+		}
+	}
+}
+
+func TestChatLogsStrayArtMessage(t *testing.T) {
+	m := newTestChat(t, answering("ok"))
+	logFile := filepath.Join(t.TempDir(), "debug.log")
+	t.Setenv("ASCII_DEBUG_LOG", logFile)
+	next, cmd := m.Update(asciiMsg(true))
+	if _, ok := next.(chatModel); !ok || cmd != nil {
+		t.Fatalf("Update(asciiMsg) with no art = %T, %v, want the chat and no command", next, cmd)
+	}
+	log, _ := os.ReadFile(logFile)
+	if !strings.Contains(string(log), "ignoring art message with no art stored") {
+		t.Errorf("debug log doesn't mention the stray message:\n%s", log)
+	}
+}
+
+func TestChatLayoutTallGreeting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greeting.txt")
+	if err := os.WriteFile(path, []byte(strings.Repeat("logo\n", 30)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ASCII_GREETING", path)
+	m := newTestChat(t, answering("unused"))
+	if m.viewport.Height < 30 {
+		t.Errorf("viewport height = %d on a tall terminal, want room for the greeting", m.viewport.Height)
+	}
+	for _, height := range []int{20, 12, 4} {
+		m.resize(tea.WindowSizeMsg{Width: 80, Height: height})
+		if want := max(3, height-chatChrome); m.viewport.Height != want {
+			t.Errorf("viewport height = %d at terminal height %d, want %d", m.viewport.Height, height, want)
+		}
+	}
+}
+
+func TestChatPromptLimitMultibyte(t *testing.T) {
+	m := newTestChat(t, answering("unused"))
+	m.textarea.SetValue(strings.Repeat("🐱", promptLimit-1))
+	typeRunes := func(s string, paste bool) {
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s), Paste: paste})
+		m = next.(chatModel)
+	}
+
+	typeRunes("é", false)
+	if got := ansi.Strip(m.focusLine()); got != "280/280" {
+		t.Errorf("counter = %q at the limit, want 280/280", got)
+	}
+	typeRunes("🐶", false)
+	if n := utf8.RuneCountInString(m.textarea.Value()); n != promptLimit || strings.Contains(m.textarea.Value(), "🐶") {
+		t.Errorf("typed past the limit to %d characters", n)
+	}
+
+	m.textarea.SetValue(strings.Repeat("世", promptLimit-2))
+	typeRunes("🐶🐶🐶🐶", true)
+	if got := m.textarea.Value(); got != strings.Repeat("世", promptLimit-2)+"🐶🐶" {
+		t.Errorf("a paste kept %d characters, want it cut to the limit", utf8.RuneCountInString(got))
+	}
+}
+
+func TestChatFocusSwitching(t *testing.T) {
+	m := newTestChat(t, answering("unused"))
+	for i := range 50 {
+		m.messages = append(m.messages, fmt.Sprintf("line %d", i))
+	}
+	m.refresh()
+	m.viewport.GotoBottom()
+	press := func(keys ...tea.KeyMsg) {
+		for _, key := range keys {
+			next, _ := m.Update(key)
+			m = next.(chatModel)
+		}
+	}
+	k := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")}
+
+	press(tea.KeyMsg{Type: tea.KeyTab})
+	if !m.viewportFocused || m.textarea.Focused() {
+		t.Fatal("tab didn't move focus to the conversation")
+	}
+	if line := ansi.Strip(m.focusLine()); !strings.HasPrefix(line, "scrolling:") {
+		t.Errorf("focus line = %q, want it to show the conversation has focus", line)
+	}
+	bottom := m.viewport.YOffset
+	press(k, tea.KeyMsg{Type: tea.KeyUp})
+	if m.viewport.YOffset != bottom-2 || m.textarea.Value() != "" {
+		t.Errorf("offset = %d, typed %q, want k and up to scroll from %d", m.viewport.YOffset, m.textarea.Value(), bottom)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyTab})
+	if m.viewportFocused || !m.textarea.Focused() {
+		t.Fatal("tab didn't move focus back to the message box")
+	}
+	press(k)
+	if m.textarea.Value() != "k" || m.viewport.YOffset != bottom-2 {
+		t.Errorf("typed %q at offset %d, want k typed and the conversation left alone", m.textarea.Value(), m.viewport.YOffset)
+	}
+	if line := ansi.Strip(m.focusLine()); line != "1/280" {
+		t.Errorf("focus line = %q, want the counter back", line)
+	}
+}
+
+func TestChatStickyScroll(t *testing.T) {
+	tests := []struct {
+		name       string
+		sticky     string
+		scrolledUp bool
+		wantBottom bool
+	}{
+		{name: "scrolled up", scrolledUp: true, wantBottom: false},
+		{name: "at the bottom", wantBottom: true},
+		{name: "scrolled up without sticky scroll", sticky: "false", scrolledUp: true, wantBottom: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestChat(t, answering("Here's a tall cat:\n```\n"+strings.Repeat("=^.^=\n", 20)+"```"))
+			t.Setenv("ASCII_STICKY_SCROLL", tt.sticky)
+			for i := range 50 {
+				m.messages = append(m.messages, fmt.Sprintf("line %d", i))
+			}
+			m.refresh()
+			m.textarea.SetValue("a tall cat")
+			next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			m = next.(chatModel)
+			if tt.scrolledUp {
+				m.viewport.LineUp(10)
+				if m.viewport.AtBottom() {
+					t.Fatal("still at the bottom after scrolling up")
+				}
+			}
+			offset := m.viewport.YOffset
+
+			next, _ = m.Update(awaitMsg[responseMsg](t, cmd))
+			m = next.(chatModel)
+			if m.viewport.AtBottom() != tt.wantBottom {
+				t.Errorf("at the bottom = %v after the response, want %v", m.viewport.AtBottom(), tt.wantBottom)
+			}
+			if !tt.wantBottom && m.viewport.YOffset != offset {
+				t.Errorf("offset = %d after the response, want it left at %d", m.viewport.YOffset, offset)
+			}
+		})
+	}
+}
+
+func TestChatExplainArt(t *testing.T) {
+	altX := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x"), Alt: true}
+	art := "```\n/\\_/\\\n( o.o )\n```"
+	client := answering("Here's a cat:\n" + art)
+	m := newTestChat(t, client)
+	if _, cmd := m.Update(altX); cmd != nil {
+		t.Fatal("alt+x sent a request with no art to explain")
+	}
+	m = sendThrough(t, m, "a cat")
+	ascii, arts := m.ascii, slices.Clone(m.arts)
+	if ascii == nil || len(arts) != 1 {
+		t.Fatalf("art = %+v, kept %q, want the cat", ascii, arts)
+	}
+
+	// The explanation quotes the art, which isn't new art
+	client.resp.Choices[0].Message.Content = "A cat sitting up:\n" + art
+	next, cmd := m.Update(altX)
+	for next.(chatModel).waiting {
+		next, cmd = next.Update(awaitMsg[responseMsg](t, cmd))
+	}
+	m = next.(chatModel)
+	sent := client.sent()
+	if len(sent) != 2 {
+		t.Fatalf("sent %d requests, want the explain request after the first", len(sent))
+	}
+	messages := sent[1].Messages
+	if last := messages[len(messages)-1]; last.Content != explainPrompt(art) || !strings.Contains(last.Content, "```\n/\\_/\\\n( o.o )\n```") {
+		t.Errorf("asked %q, want the art quoted", last.Content)
+	}
+	earlier := []string{}
+	for _, message := range messages[:len(messages)-1] {
+		earlier = append(earlier, message.Content)
+	}
+	if !slices.Contains(earlier, "a cat") || !slices.Contains(earlier, "Here's a cat:\n"+art) {
+		t.Errorf("sent %q before the question, want the conversation so far", earlier)
+	}
+	if m.ascii != ascii || !slices.Equal(m.arts, arts) {
+		t.Errorf("art = %+v, kept %q after the explanation, want them unchanged", m.ascii, m.arts)
+	}
+}
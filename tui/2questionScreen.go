@@ -5,23 +5,64 @@ package tui
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
+	db "github.com/ericulley/ascii/data"
 )
 
 type questionModel struct {
-	asciiArt      string
+	record        db.AsciiRecord
 	questions     []string
 	questionIndex int
 	choices       []string
 	cursorIndex   int
 	width         int
 	height        int
+	typewriter    time.Duration
+	revealed      int
+	scaled        bool
+	// The art as it was before it was fit to the screen, while it's fit
+	unscaled    string
+	lineNumbers bool
+	// How far along art that came back as one long line it's scrolled
+	offset int
+	// Whether escape sequences in the art are shown instead of acted on
+	escapes bool
+	// Picking a region of the art to redraw, from the anchor to the cursor
+	selecting bool
+	anchored  bool
+	anchorRow int
+	anchorCol int
+	cursorRow int
+	cursorCol int
+	// Waiting on the model to redraw the selected region
+	redrawing bool
+	notice    string
+	// Choosing colors to show the art in, and the colors chosen when there's
+	// no chat to keep them
+	picking        bool
+	pickBackground bool
+	pickIndex      int
+	foreground     string
+	background     string
+	// The chat session to return to, if there is one
+	chat *chatModel
 }
 
-func NewQuestionModel(art string) questionModel {
+type revealMsg struct{}
+
+// artChrome is the number of lines around the art taken up by the fences,
+// the size warning and the question.
+const artChrome = 8
+
+func NewQuestionModel(record db.AsciiRecord) questionModel {
 	return questionModel{
-		asciiArt: art,
+		record: record,
 		questions: []string{
 			"Would you like to save this art?",
 			"Enter a name: ",
@@ -32,6 +73,28 @@ func NewQuestionModel(art string) questionModel {
 		cursorIndex:   0,
 		width:         80,
 		height:        10,
+		// Delay between revealed art lines, off unless ASCII_TYPEWRITER_MS is set
+		typewriter:     time.Duration(envInt("ASCII_TYPEWRITER_MS", 0)) * time.Millisecond,
+		revealed:       0,
+		scaled:         false,
+		unscaled:       "",
+		lineNumbers:    false,
+		offset:         0,
+		escapes:        false,
+		selecting:      false,
+		anchored:       false,
+		anchorRow:      0,
+		anchorCol:      0,
+		cursorRow:      0,
+		cursorCol:      0,
+		redrawing:      false,
+		notice:         "",
+		picking:        false,
+		pickBackground: false,
+		pickIndex:      0,
+		foreground:     "",
+		background:     "",
+		chat:           nil,
 	}
 }
 
@@ -41,16 +104,138 @@ func (m questionModel) Init() tea.Cmd {
 
 func (m questionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case asciiMsg:
+		// Start revealing the art line by line
+		if m.revealing() {
+			return m, revealTick(m.typewriter)
+		}
+	case revealMsg:
+		if m.revealing() {
+			m.revealed++
+			if m.revealing() {
+				return m, revealTick(m.typewriter)
+			}
+		}
+	case regionMsg:
+		m.redrawing = false
+		switch {
+		case msg.err != nil:
+			m.notice = fmt.Sprintf("Couldn't redraw the region: %v", msg.err)
+		case msg.from != m.record.Art:
+			m.notice = "The art changed while the region was being redrawn, so the new part was left out."
+		default:
+			m.record.Art = msg.art
+			// The redrawn art is what undoing a fit would go back to now
+			m.unscaled = ""
+			m.notice = "Redrew the selected region."
+		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		if m.unscaled != "" {
+			// Keep fit art fitting the new size
+			m.record.Art = scaleArt(m.unscaled, m.fit())
+		}
 	// Is it a key press?
 	case tea.KeyMsg:
+		// Any key skips the rest of the animation
+		if m.revealing() {
+			m.revealed = len(m.artLines())
+			return m, nil
+		}
+		if m.redrawing {
+			// The art stays put until the redrawn region is in
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+		if m.selecting {
+			return m.updateSelection(msg)
+		}
+		if m.picking {
+			return m.updatePicker(msg)
+		}
 		// Cool, what was the actual key pressed?
 		switch msg.String() {
 		// These keys should exit the program.
-		case "esc", "ctrl+c", "q":
+		case "ctrl+c", "q":
 			return m, tea.Quit
+		// The "esc" key discards the art and goes back to the chat
+		case "esc":
+			if m.chat != nil {
+				return returnToChat(*m.chat)
+			}
+			return m, tea.Quit
+		// The "s" key scales art that doesn't fit the terminal
+		case "s":
+			m.scaled = !m.scaled
+		// The "f" key scales the art itself down to fit the screen, so it's
+		// saved that way too. "u" undoes it
+		case "f":
+			if m.unscaled == "" {
+				if factor := m.fit(); factor > 1 && !longLine(m.record.Art, m.width) {
+					m.unscaled = m.record.Art
+					m.record.Art = scaleArt(m.record.Art, factor)
+					m.notice = fmt.Sprintf("Fit the art to the screen at 1/%d size. Press u to undo.", factor)
+				}
+			}
+		case "u":
+			if m.unscaled != "" {
+				m.record.Art = m.unscaled
+				m.unscaled = ""
+				m.notice = "Restored the art to full size."
+			}
+		// The "w" key wraps art that came back as one long line, "←/→" scroll
+		// along it instead
+		case "w":
+			if longLine(m.record.Art, m.width) {
+				m.record.Art = wrapArt(m.record.Art, m.width)
+				m.offset = 0
+				m.notice = fmt.Sprintf("Wrapped the art at %d columns.", m.width)
+			}
+		case "left", "h":
+			m.offset = max(0, m.offset-max(1, m.width/2))
+		case "right", "l":
+			if w, _ := artSize(m.record.Art); longLine(m.record.Art, m.width) {
+				m.offset = min(max(0, w-m.width), m.offset+max(1, m.width/2))
+			}
+		// The "e" key shows the escape sequences in the art, for debugging
+		// colors. Only with ASCII_DEBUG on
+		case "e":
+			if envBool("ASCII_DEBUG", false) {
+				m.escapes = !m.escapes
+			}
+		// The "n" key shows line numbers next to the art, they're never saved
+		case "n":
+			m.lineNumbers = !m.lineNumbers
+		// The "c" key copies the art as is, "C" copies it fenced for pasting
+		// into markdown
+		case "c", "C":
+			fenced := msg.String() == "C"
+			if err := clipboard.WriteAll(copyText(m.record.Art, fenced)); err != nil {
+				m.notice = fmt.Sprintf("Couldn't copy the art: %v", err)
+			} else if fenced {
+				m.notice = "Copied the art as a code block."
+			} else {
+				m.notice = "Copied the art."
+			}
+		// The "p" key picks colors to show the art in for the session
+		case "p":
+			if len(palette()) == 0 {
+				m.notice = "This terminal doesn't support colors."
+			} else {
+				m.picking = true
+				m.pickIndex = 0
+				m.notice = ""
+			}
+		// The "r" key starts selecting a region of the art to redraw
+		case "r":
+			if m.chat != nil && m.record.Art != "" {
+				m.selecting = true
+				m.anchored = false
+				m.notice = ""
+			}
 		// The "up" and "k" keys move the cursor up
 		case "up", "k":
 			if m.cursorIndex > 0 {
@@ -66,7 +251,9 @@ func (m questionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			switch m.questionIndex {
 			case 0: // "Would you like to save this art?"
 				if m.cursorIndex == 0 {
-					return NewPromptModel(m.asciiArt).Update(msg)
+					prompt := NewPromptModel(m.record)
+					prompt.chat = m.chat
+					return prompt.Update(msg)
 				} else if m.cursorIndex == 1 {
 					m.questionIndex = 2
 					return m, nil
@@ -78,6 +265,9 @@ func (m questionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.cursorIndex == 0 {
 					return m, tea.Quit
 				} else if m.cursorIndex == 1 {
+					if m.chat != nil {
+						return returnToChat(*m.chat)
+					}
 					return NewChatModel().Update(msg)
 				}
 			}
@@ -92,9 +282,60 @@ func (m questionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m questionModel) View() string {
 	var s string
+	if small, ok := tooSmall(m.width, m.height); ok {
+		return small
+	}
 	// Display ascii art
-	if m.asciiArt != "" {
-		s = fmt.Sprintf(m.asciiArt + "\n\n")
+	if m.revealing() {
+		return strings.Join(m.artLines()[:m.revealed], "\n") + "\n"
+	}
+	if m.notice != "" {
+		s = m.notice + "\n"
+	}
+	if m.selecting {
+		help := "Move with the arrow keys and press space to mark a corner."
+		if m.anchored {
+			help = "Move to the other corner and press enter to redraw the region."
+		}
+		return s + highlightRegion(m.record.Art, m.selection()) + "\n\n" + help + " esc to cancel.\n"
+	}
+	if m.picking {
+		layer := "foreground"
+		if m.pickBackground {
+			layer = "background"
+		}
+		// The art is previewed in the color under the cursor
+		foreground, background := m.pickedColors()
+		return s + colorArt(m.record.Art, foreground, background) + "\n\n" +
+			"Picking the " + layer + " color\n" + paletteGrid(palette(), m.pickIndex) + "\n\n" +
+			statusStyle.Render("arrows to choose • tab to switch foreground/background • enter to use • esc to cancel") + "\n"
+	}
+	if m.record.Art != "" {
+		art := m.record.Art
+		if w, _ := artSize(art); longLine(art, m.width) {
+			// Scaling would leave next to nothing of a single line
+			art = scrollArt(art, m.offset, m.width)
+			s += fmt.Sprintf("This art is one line %d characters long, showing %d-%d. Press w to wrap it at %d columns or ←/→ to scroll.\n",
+				w, m.offset+1, min(w, m.offset+m.width), m.width)
+		} else if factor := fitFactor(art, m.width, m.height-artChrome); factor > 1 {
+			w, h := artSize(art)
+			if m.scaled {
+				art = scaleArt(art, factor)
+				s += fmt.Sprintf("Showing the art at 1/%d size. Press s to show it at full size.\n", factor)
+			} else {
+				s += fmt.Sprintf("This art is %dx%d but the terminal is %dx%d. Press s to scale it down or f to fit it to the screen.\n", w, h, m.width, m.height)
+			}
+		}
+		if foreground, background := m.artColors(); foreground != "" || background != "" {
+			art = colorArt(art, foreground, background)
+		}
+		if m.escapes {
+			art = showEscapes(art)
+		}
+		if m.lineNumbers {
+			art = numberLines(art)
+		}
+		s += art + "\n\n"
 	}
 	// Display the prompt
 	s = s + m.questions[m.questionIndex] + "\n"
@@ -125,3 +366,96 @@ func (m questionModel) View() string {
 	// Send the UI for rendering
 	return s
 }
+
+// updateSelection handles keys while a region of the art is being selected.
+// The selection stays inside the art as it's drawn.
+func (m questionModel) updateSelection(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	lines := artCells(m.record.Art, 0)
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.selecting = false
+	case "up", "k":
+		m.cursorRow = max(0, m.cursorRow-1)
+	case "down", "j":
+		m.cursorRow = min(len(lines)-1, m.cursorRow+1)
+	case "left", "h":
+		m.cursorCol = max(0, m.cursorCol-1)
+	case "right", "l":
+		m.cursorCol++
+	case " ":
+		m.anchored = true
+		m.anchorRow = m.cursorRow
+		m.anchorCol = m.cursorCol
+	case "enter":
+		if !m.anchored {
+			return m, nil
+		}
+		m.selecting = false
+		m.redrawing = true
+		m.notice = "Redrawing the selected region..."
+		return m, regenerateRegion(m.chat, m.record.Art, m.selection())
+	}
+	// Don't let the cursor wander past the widest line
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+	m.cursorCol = min(m.cursorCol, max(0, width-1))
+	return m, nil
+}
+
+// fit returns the factor the art before any fitting is scaled down by to fit
+// the screen.
+func (m questionModel) fit() int {
+	art := m.record.Art
+	if m.unscaled != "" {
+		art = m.unscaled
+	}
+	return fitFactor(art, m.width, m.height-artChrome)
+}
+
+// selection is the region between the anchor and the cursor, or just the
+// cursor before a corner has been marked.
+func (m questionModel) selection() region {
+	if !m.anchored {
+		return newRegion(m.cursorRow, m.cursorCol, m.cursorRow, m.cursorCol)
+	}
+	return newRegion(m.anchorRow, m.anchorCol, m.cursorRow, m.cursorCol)
+}
+
+// returnToChat resumes a chat session, dropping the art that was pending.
+func returnToChat(chat chatModel) (tea.Model, tea.Cmd) {
+	chat.ascii = nil
+	// There may be new art to pin
+	chat.layout()
+	// Snapshots don't run while away from the chat, start them up again
+	autosave := chat.restartAutosave()
+	return chat, tea.Batch(textarea.Blink, autosave)
+}
+
+// revealing reports whether the typewriter animation is still showing the art.
+func (m questionModel) revealing() bool {
+	return m.typewriter > 0 && m.revealed < len(m.artLines())
+}
+
+func (m questionModel) artLines() []string {
+	return strings.Split(m.record.Art, "\n")
+}
+
+func revealTick(d time.Duration) tea.Cmd {
+	return tea.Tick(d, func(time.Time) tea.Msg {
+		return revealMsg{}
+	})
+}
+
+// copyText is art the way it's copied, either raw or wrapped in a fence tagged
+// with ASCII_COPY_LANG, so it keeps its shape when pasted into markdown.
+func copyText(art string, fenced bool) string {
+	raw := stripFence(art)
+	if !fenced {
+		return raw
+	}
+	return fence + os.Getenv("ASCII_COPY_LANG") + "\n" + raw + "\n" + fence
+}
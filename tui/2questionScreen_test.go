@@ -0,0 +1,147 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	db "github.com/ericulley/ascii/data"
+)
+
+// selectRegion has a question screen for art mark the region from its top
+// left corner to the cell at row and col, and press enter to redraw it.
+func selectRegion(t *testing.T, client ChatClient, art string, row, col int) (questionModel, tea.Cmd) {
+	t.Helper()
+	chat := newTestChat(t, client)
+	m := NewQuestionModel(db.AsciiRecord{Art: art})
+	m.chat = &chat
+	keys := []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("r")}, {Type: tea.KeySpace, Runes: []rune(" ")}}
+	for i := 0; i < row; i++ {
+		keys = append(keys, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	for i := 0; i < col; i++ {
+		keys = append(keys, tea.KeyMsg{Type: tea.KeyRight})
+	}
+	var next tea.Model = m
+	for _, key := range keys {
+		next, _ = next.Update(key)
+	}
+	next, cmd := next.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return next.(questionModel), cmd
+}
+
+func TestRegionRedraw(t *testing.T) {
+	m, cmd := selectRegion(t, answering("```\n##\n##\n```"), "```\nabc\ndef\nghi\n```", 1, 1)
+	if !m.redrawing || cmd == nil {
+		t.Fatalf("redrawing = %v, cmd = %v, want a request on its way", m.redrawing, cmd)
+	}
+	// Keys wait for the redrawn region
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if next.(questionModel).selecting {
+		t.Errorf("started selecting again while redrawing")
+	}
+	next, _ = next.Update(awaitMsg[regionMsg](t, cmd))
+	m = next.(questionModel)
+	if m.redrawing {
+		t.Errorf("still redrawing after the region came back")
+	}
+	if want := "```\n##c\n##f\nghi\n```"; m.record.Art != want {
+		t.Errorf("art = %q, want %q", m.record.Art, want)
+	}
+}
+
+func TestRegionRedrawError(t *testing.T) {
+	tests := []struct {
+		name   string
+		client ChatClient
+		want   string
+	}{
+		{name: "request fails", client: failing(errors.New("bad request")), want: "Couldn't redraw the region: bad request"},
+		{name: "no art", client: answering("I can't draw that"), want: "Couldn't redraw the region: no ascii art found in the response"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, cmd := selectRegion(t, tt.client, "abc\ndef", 0, 1)
+			next, _ := m.Update(awaitMsg[regionMsg](t, cmd))
+			m = next.(questionModel)
+			if m.redrawing || m.record.Art != "abc\ndef" || m.notice != tt.want {
+				t.Errorf("redrawing = %v, art = %q, notice = %q, want the art unchanged and %q", m.redrawing, m.record.Art, m.notice, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumberLines(t *testing.T) {
+	art := "```\n" + strings.Repeat("#\n", 9) + "#\n```"
+	lines := strings.Split(ansi.Strip(numberLines(art)), "\n")
+	if len(lines) != 10 || lines[0] != " 1 #" || lines[9] != "10 #" {
+		t.Errorf("numberLines() = %q, want a two column gutter", lines)
+	}
+	if got := ansi.Strip(numberLines("a\nb")); got != "1 a\n2 b" {
+		t.Errorf("numberLines() = %q, want a one column gutter", got)
+	}
+}
+
+func TestLineNumbersNotSaved(t *testing.T) {
+	testEnv(t)
+	art := "```\n" + strings.Repeat("/\\\n", 11) + "\\/\n```"
+	var next tea.Model = NewQuestionModel(db.AsciiRecord{Art: art})
+	next, _ = next.Update(tea.WindowSizeMsg{Width: 80, Height: 40})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	m := next.(questionModel)
+	if view := ansi.Strip(m.View()); !strings.Contains(view, " 1 /\\") || !strings.Contains(view, "12 \\/") {
+		t.Fatalf("View() doesn't number the lines:\n%s", view)
+	}
+	if m.record.Art != art || copyText(m.record.Art, false) != stripFence(art) {
+		t.Errorf("art = %q, want it without line numbers", m.record.Art)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if prompt, ok := next.(promptModel); !ok || prompt.record.Art != art {
+		t.Errorf("saving %T, want the art without line numbers", next)
+	}
+}
+
+func TestLongSingleLineArt(t *testing.T) {
+	line := strings.Repeat("0123456789", 500)
+	art := fence + "\n" + line + "\n" + fence
+	if !longLine(art, 80) || longLine(art, 5000) {
+		t.Fatal("longLine() didn't tell a 5000 character line from one that fits")
+	}
+	if got := scrollArt(art, 4990, 80); got != "0123456789" {
+		t.Errorf("scrollArt() at the end = %q, want the last 10 columns", got)
+	}
+	wrapped := stripFence(wrapArt(art, 80))
+	if lines := strings.Split(wrapped, "\n"); len(lines) != 63 || lines[0] != line[:80] || lines[62] != line[4960:] {
+		t.Errorf("wrapArt() made %d lines, want 63 of up to 80 columns", len(lines))
+	}
+
+	start := time.Now()
+	var next tea.Model = NewQuestionModel(db.AsciiRecord{Art: art})
+	next, _ = next.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	view := next.View()
+	if !strings.Contains(view, "one line 5000 characters long, showing 1-80") {
+		t.Errorf("View() doesn't offer to wrap or scroll:\n%s", view)
+	}
+	if !strings.Contains(view, "\n"+line[:80]+"\n") || strings.Contains(view, line[:81]) {
+		t.Errorf("View() doesn't show the first 80 columns of the art")
+	}
+	for range 3 {
+		next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRight})
+	}
+	if m := next.(questionModel); m.offset != 120 || !strings.Contains(m.View(), "showing 121-200") {
+		t.Errorf("offset = %d after scrolling right 3 times, want 120", m.offset)
+	}
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")})
+	if m := next.(questionModel); m.offset != 0 || strings.Count(m.record.Art, "\n") != 64 {
+		t.Errorf("w left the art as %d lines, want it wrapped at 80 columns", strings.Count(m.record.Art, "\n")-1)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("showing the art took %s", elapsed)
+	}
+}
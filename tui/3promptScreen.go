@@ -4,6 +4,12 @@ Copyright © 2024 Eric Culley <https://github.com/ericulley>
 package tui
 
 import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -11,30 +17,38 @@ import (
 )
 
 type promptModel struct {
-	asciiArt    string
+	record      db.AsciiRecord
 	prompts     []string
 	promptIndex int
 	answerField textinput.Model
 	width       int
 	height      int
+	invalid     bool
+	// Each piece of art to save under the name, numbered, instead of record.Art
+	variants []string
+	// The chat session to return to if saving is cancelled
+	chat *chatModel
 }
 
 func (m promptModel) Init() tea.Cmd {
 	return textinput.Blink
 }
 
-func NewPromptModel(art string) *promptModel {
+func NewPromptModel(record db.AsciiRecord) *promptModel {
 	answerField := textinput.New()
 	answerField.Placeholder = "Your answer here"
 	answerField.Focus()
 	answerField.Width = 128
 	return &promptModel{
-		asciiArt:    art,
+		record:      record,
 		prompts:     []string{"Enter a name to store this art: ", "Success! Your art was stored under "},
 		promptIndex: 0,
 		answerField: answerField,
 		width:       80,
 		height:      10,
+		invalid:     false,
+		variants:    nil,
+		chat:        nil,
 	}
 }
 
@@ -51,11 +65,36 @@ func (m promptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case "ctrl+c":
 			return m, tea.Quit
+		case "esc":
+			// Cancel without saving, the typed name goes with it
+			if m.chat != nil {
+				return returnToChat(*m.chat)
+			}
+			return m, tea.Quit
 		case "enter":
 			if m.answerField.Value() != "" {
+				name := sanitizeName(m.answerField.Value())
+				if name == "" {
+					// Nothing usable was left, ask again
+					m.invalid = true
+					m.answerField.Reset()
+					return m, nil
+				}
 				// update database & return success message
 				m.promptIndex = 1
-				db.SaveArtToDB(db.AsciiRecord{Name: m.answerField.Value(), Art: m.asciiArt})
+				m.invalid = false
+				m.record.Name = name
+				if len(m.variants) > 0 {
+					for i, art := range m.variants {
+						record := m.record
+						record.Name = numberedName(name, i+1)
+						record.Art = art
+						saveRecord(record)
+					}
+					m.record.Name = numberedName(name, 1) + " to " + numberedName(name, len(m.variants))
+					return m, nil
+				}
+				saveRecord(m.record)
 			}
 			return m, nil
 		}
@@ -70,9 +109,63 @@ func (m promptModel) View() string {
 	}
 	var prompt string
 	if m.promptIndex == 1 {
-		prompt = m.prompts[m.promptIndex] + m.answerField.Value()
+		prompt = m.prompts[m.promptIndex] + m.record.Name
+	} else if m.invalid {
+		prompt = "That name can't be used, please enter another: "
 	} else {
 		prompt = m.prompts[m.promptIndex]
 	}
 	return lipgloss.JoinVertical(lipgloss.Left, prompt, m.answerField.View())
 }
+
+// saveRecord stores art in the database. Some art relies on trailing spaces,
+// so trimming them can be disabled.
+func saveRecord(record db.AsciiRecord) {
+	if envBool("ASCII_TRIM_TRAILING", true) {
+		record.Art = trimTrailingSpace(record.Art)
+	}
+	db.SaveArtToDB(record)
+}
+
+// numberedName is the name the nth of several variants is saved under, counting
+// from 1, before the extension.
+func numberedName(name string, n int) string {
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), n, ext)
+}
+
+// artExt ends the name of every piece of saved art, so it's ready to use as
+// the name of a text file.
+const artExt = ".txt"
+
+// reservedNames can't be used as file names on Windows, whatever their
+// extension.
+var reservedNames = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
+
+// sanitizeName makes a user provided name safe to save art under, and to use
+// as a file name. Path separators and characters that aren't allowed in file
+// names are replaced, and surrounding spaces and dots are trimmed so names
+// like "../x" can't point outside of where art is kept. The name gets a .txt
+// extension if it doesn't have one. Names with nothing usable left, or that
+// are reserved, come back empty.
+func sanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || unicode.IsControl(r) {
+			return '-'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, " .-")
+	base, _, _ := strings.Cut(name, ".")
+	if name == "" || slices.Contains(reservedNames, strings.ToUpper(strings.TrimSpace(base))) {
+		return ""
+	}
+	if !strings.EqualFold(filepath.Ext(name), artExt) {
+		name += artExt
+	}
+	return name
+}
@@ -0,0 +1,166 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	db "github.com/ericulley/ascii/data"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "cat", "cat.txt"},
+		{"keeps extension", "cat.txt", "cat.txt"},
+		{"keeps extension case", "cat.TXT", "cat.TXT"},
+		{"other extension", "cat.art", "cat.art.txt"},
+		{"forward slash", "a/b", "a-b.txt"},
+		{"backslash", `a\b`, "a-b.txt"},
+		{"parent dir", "../x", "x.txt"},
+		{"windows parent dir", `..\x`, "x.txt"},
+		{"only dots", "..", ""},
+		{"only separators", "/", ""},
+		{"control characters", "c\x00a\tt\n", "c-a-t.txt"},
+		{"forbidden characters", `a:b*c?d"e<f>g|h`, "a-b-c-d-e-f-g-h.txt"},
+		{"surrounding spaces", "  cat  ", "cat.txt"},
+		{"empty", "", ""},
+		{"blank", "   ", ""},
+		{"reserved", "CON", ""},
+		{"reserved lower case", "nul", ""},
+		{"reserved with extension", "com1.txt", ""},
+		{"reserved prefix", "console", "console.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeName(tt.in); got != tt.want {
+				t.Errorf("sanitizeName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumberedName(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"cat.txt", 1, "cat-1.txt"},
+		{"cat.txt", 12, "cat-12.txt"},
+		{"cat", 2, "cat-2"},
+	}
+	for _, tt := range tests {
+		if got := numberedName(tt.in, tt.n); got != tt.want {
+			t.Errorf("numberedName(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSaveAllVariants(t *testing.T) {
+	testEnv(t)
+	testDB(t)
+	arts := []string{"```\na\n```", "```\nb\n```", "```\nc\n```"}
+	var next tea.Model = NewSheetModel(arts, nil)
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("a")},
+		{Type: tea.KeyRunes, Runes: []rune("cat")},
+		{Type: tea.KeyEnter},
+	} {
+		next, _ = next.Update(key)
+	}
+	next, _ = next.Update(tea.WindowSizeMsg{Width: 80, Height: 10})
+	if view := next.View(); !strings.Contains(view, "stored under cat-1.txt to cat-3.txt") {
+		t.Errorf("View() = %q, want the numbered names", view)
+	}
+	records, err := db.ListArtRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(arts) {
+		t.Fatalf("saved %d records, want %d", len(records), len(arts))
+	}
+	for i, record := range records {
+		if want := numberedName("cat.txt", i+1); record.Name != want || record.Art != arts[i] {
+			t.Errorf("record %d = %q %q, want %q %q", i, record.Name, record.Art, want, arts[i])
+		}
+	}
+}
+
+func TestSaveRecordTrailingSpace(t *testing.T) {
+	art := "```\n  o   o  \n   \\_/   \n```"
+	for _, tt := range []struct {
+		trim string
+		want string
+	}{
+		{trim: "", want: "```\n  o   o\n   \\_/\n```"},
+		{trim: "false", want: art},
+	} {
+		t.Run("trim="+tt.trim, func(t *testing.T) {
+			testEnv(t)
+			testDB(t)
+			t.Setenv("ASCII_TRIM_TRAILING", tt.trim)
+			saveRecord(db.AsciiRecord{Name: "face.txt", Art: art})
+			records, err := db.ListArtRecords()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 || records[0].Art != tt.want {
+				t.Errorf("saved %+v, want the art %q", records, tt.want)
+			}
+		})
+	}
+}
+
+func TestCancelSaving(t *testing.T) {
+	esc := tea.KeyMsg{Type: tea.KeyEsc}
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+	tests := []struct {
+		name string
+		keys []tea.KeyMsg
+	}{
+		{name: "from the question", keys: []tea.KeyMsg{esc}},
+		{name: "while naming it", keys: []tea.KeyMsg{enter, {Type: tea.KeyRunes, Runes: []rune("cat")}, esc}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := sendThrough(t, newTestChat(t, answering("```\n=^.^=\n```")), "a cat")
+			testDB(t)
+			if chat.ascii == nil {
+				t.Fatal("no art to save")
+			}
+			m := NewQuestionModel(db.AsciiRecord{Art: chat.ascii.art})
+			m.chat = &chat
+			var next tea.Model = m
+			for _, key := range tt.keys {
+				next, _ = next.Update(key)
+			}
+			back, ok := next.(chatModel)
+			if !ok {
+				t.Fatalf("esc went to %T, want the chat", next)
+			}
+			if back.ascii != nil {
+				t.Errorf("art = %+v back in the chat, want it cleared", back.ascii)
+			}
+			if value := back.textarea.Value(); value != "" {
+				t.Errorf("message box = %q back in the chat, want the typed name dropped", value)
+			}
+			if records, err := db.ListArtRecords(); err != nil || len(records) != 0 {
+				t.Errorf("saved %+v, %v, want nothing saved", records, err)
+			}
+			// Saving the next art starts from an empty name
+			prompt := NewQuestionModel(db.AsciiRecord{Art: "```\n=^.^=\n```"})
+			prompt.chat = &back
+			named, _ := prompt.Update(enter)
+			if value := named.(promptModel).answerField.Value(); value != "" {
+				t.Errorf("name = %q when saving again, want the typed name dropped", value)
+			}
+		})
+	}
+}
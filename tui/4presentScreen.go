@@ -0,0 +1,76 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type presentModel struct {
+	arts     []string
+	index    int
+	interval time.Duration
+	width    int
+	height   int
+}
+
+type nextArtMsg struct{}
+
+// NewPresentModel displays arts one at a time, centered on the screen. When
+// there is more than one art and interval is set, they are cycled on a timer.
+// The caller's slice is left as it is.
+func NewPresentModel(arts []string, interval time.Duration) presentModel {
+	stripped := make([]string, len(arts))
+	for i, art := range arts {
+		stripped[i] = stripFence(art)
+	}
+	return presentModel{
+		arts:     stripped,
+		index:    0,
+		interval: interval,
+		width:    0,
+		height:   0,
+	}
+}
+
+func (m presentModel) Init() tea.Cmd {
+	return m.nextArt()
+}
+
+func (m presentModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case nextArtMsg:
+		m.index = (m.index + 1) % len(m.arts)
+		return m, m.nextArt()
+	case tea.KeyMsg:
+		// Only quitting is allowed, everything else is ignored
+		switch msg.String() {
+		case "esc", "ctrl+c", "q":
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+func (m presentModel) View() string {
+	if m.width == 0 || len(m.arts) == 0 {
+		return ""
+	}
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.arts[m.index])
+}
+
+func (m presentModel) nextArt() tea.Cmd {
+	if m.interval <= 0 || len(m.arts) < 2 {
+		return nil
+	}
+	return tea.Tick(m.interval, func(time.Time) tea.Msg {
+		return nextArtMsg{}
+	})
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestNewPresentModelKeepsArts(t *testing.T) {
+	arts := []string{fence + "\none\n" + fence, "two"}
+	m := NewPresentModel(arts, time.Second)
+	if arts[0] != fence+"\none\n"+fence {
+		t.Errorf("caller's art changed to %q", arts[0])
+	}
+	if m.arts[0] != "one" {
+		t.Errorf("presented art = %q, want its fence stripped", m.arts[0])
+	}
+}
+
+func TestPresentUpdate(t *testing.T) {
+	m := NewPresentModel([]string{"one", "two"}, time.Second)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 21, Height: 5})
+	m = next.(presentModel)
+	lines := strings.Split(m.View(), "\n")
+	if len(lines) != 5 || lines[2] != strings.Repeat(" ", 9)+"one"+strings.Repeat(" ", 9) {
+		t.Errorf("View() = %q, want the art centered in 21x5", lines)
+	}
+
+	for _, key := range []tea.KeyMsg{{Type: tea.KeyEnter}, {Type: tea.KeyRunes, Runes: []rune("x")}} {
+		if _, cmd := m.Update(key); cmd != nil {
+			t.Errorf("%q returned a command, want it ignored", key)
+		}
+	}
+	for _, key := range []tea.KeyMsg{{Type: tea.KeyEsc}, {Type: tea.KeyCtrlC}, {Type: tea.KeyRunes, Runes: []rune("q")}} {
+		if _, cmd := m.Update(key); cmd == nil {
+			t.Errorf("%q didn't quit", key)
+		} else if _, ok := cmd().(tea.QuitMsg); !ok {
+			t.Errorf("%q didn't quit", key)
+		}
+	}
+
+	next, cmd := m.Update(nextArtMsg{})
+	if m = next.(presentModel); m.index != 1 || cmd == nil {
+		t.Errorf("index = %d after the timer, want 1 and another tick", m.index)
+	}
+	next, _ = m.Update(nextArtMsg{})
+	if m = next.(presentModel); m.index != 0 {
+		t.Errorf("index = %d after the last art, want it to wrap to 0", m.index)
+	}
+}
+
+func TestPresentInitWithoutCycling(t *testing.T) {
+	if cmd := NewPresentModel([]string{"one"}, time.Second).Init(); cmd != nil {
+		t.Error("a single art started the timer")
+	}
+	if cmd := NewPresentModel([]string{"one", "two"}, 0).Init(); cmd != nil {
+		t.Error("no interval started the timer")
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	db "github.com/ericulley/ascii/data"
+)
+
+var selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("5"))
+
+type galleryModel struct {
+	records       []db.AsciiRecord
+	cursorIndex   int
+	onlyFavorites bool
+	err           error
+	width         int
+	height        int
+}
+
+// NewGalleryModel browses the saved art, with a preview of the selected piece.
+func NewGalleryModel(records []db.AsciiRecord) galleryModel {
+	return galleryModel{
+		records:       records,
+		cursorIndex:   0,
+		onlyFavorites: false,
+		err:           nil,
+		width:         80,
+		height:        10,
+	}
+}
+
+func (m galleryModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m galleryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		visible := m.visible()
+		switch msg.String() {
+		case "esc", "ctrl+c", "q":
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursorIndex > 0 {
+				m.cursorIndex--
+			}
+		case "down", "j":
+			if m.cursorIndex < len(visible)-1 {
+				m.cursorIndex++
+			}
+		// The "f" key stars or unstars the selected art
+		case "f":
+			if len(visible) == 0 {
+				return m, nil
+			}
+			i := visible[m.cursorIndex]
+			m.err = db.SetFavorite(m.records[i].Id, !m.records[i].Favorite)
+			if m.err == nil {
+				m.records[i].Favorite = !m.records[i].Favorite
+			}
+			// Unstarring may have removed it from the filtered list
+			m.cursorIndex = min(m.cursorIndex, max(0, len(m.visible())-1))
+		// The "c" key compares the selected art with the rest side by side
+		case "c":
+			if len(visible) < 2 {
+				return m, nil
+			}
+			titles, arts := []string{}, []string{}
+			for _, i := range visible {
+				titles = append(titles, m.records[i].Name)
+				arts = append(arts, m.records[i].Art)
+			}
+			compare := NewCompareModel(titles, arts)
+			// The selected art next to the one after it
+			compare.left = m.cursorIndex
+			compare.right = (m.cursorIndex + 1) % len(arts)
+			compare.gallery = &m
+			compare.width, compare.height = m.width, m.height
+			return compare, nil
+		// The "*" key only shows favorites
+		case "*":
+			m.onlyFavorites = !m.onlyFavorites
+			m.cursorIndex = 0
+		}
+	}
+	return m, nil
+}
+
+func (m galleryModel) View() string {
+	visible := m.visible()
+	if len(visible) == 0 {
+		if m.onlyFavorites {
+			return "No favorites yet, press * to show all art\n"
+		}
+		return "No saved art yet, run `ascii create` to make some\n"
+	}
+	list := ""
+	for i, index := range visible {
+		record := m.records[index]
+		cursor := " "
+		if m.cursorIndex == i {
+			cursor = ">"
+		}
+		star := " "
+		if record.Favorite {
+			star = "★"
+		}
+		row := fmt.Sprintf("%s %s %d %s", cursor, star, record.Id, record.Name)
+		if m.cursorIndex == i {
+			row = selectedStyle.Render(row)
+		}
+		list += row + "\n"
+	}
+	preview := previewStyle.Render(stripFence(m.records[visible[m.cursorIndex]].Art))
+	help := statusStyle.Render("f: favorite • *: only favorites • c: compare • q: quit")
+	if m.err != nil {
+		help = statusStyle.Render(fmt.Sprintf("Could not update favorite: %v", m.err))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, list, "  ", preview) + "\n\n" + help + "\n"
+}
+
+// visible returns the indexes of the records shown with the current filter.
+func (m galleryModel) visible() []int {
+	indexes := []int{}
+	for i, record := range m.records {
+		if !m.onlyFavorites || record.Favorite {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	db "github.com/ericulley/ascii/data"
+)
+
+func TestGalleryFavorites(t *testing.T) {
+	testEnv(t)
+	testDB(t)
+	for _, name := range []string{"cat.txt", "dog.txt", "owl.txt"} {
+		if err := db.SaveArtToDB(db.AsciiRecord{Name: name, Art: "```\n" + name + "\n```"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	open := func() galleryModel {
+		t.Helper()
+		records, err := db.ListArtRecords()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewGalleryModel(records)
+	}
+	press := func(m galleryModel, keys ...string) galleryModel {
+		for _, key := range keys {
+			next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+			m = next.(galleryModel)
+		}
+		return m
+	}
+	favorites := func() []string {
+		t.Helper()
+		names := []string{}
+		for _, record := range open().records {
+			if record.Favorite {
+				names = append(names, record.Name)
+			}
+		}
+		return names
+	}
+
+	m := press(open(), "j", "f")
+	if m.err != nil {
+		t.Fatalf("f failed: %v", m.err)
+	}
+	if got := favorites(); len(got) != 1 || got[0] != "dog.txt" {
+		t.Fatalf("favorites = %v read back, want the dog starred", got)
+	}
+
+	m = press(open(), "*")
+	if visible := m.visible(); len(visible) != 1 || m.records[visible[0]].Name != "dog.txt" {
+		t.Errorf("visible = %v with only favorites, want just the dog", visible)
+	}
+	if view := m.View(); !strings.Contains(view, "★") || !strings.Contains(view, "dog.txt") || strings.Contains(view, "cat.txt") || strings.Contains(view, "owl.txt") {
+		t.Errorf("View() = %q, want only the starred dog listed", view)
+	}
+
+	m = press(m, "f")
+	if view := m.View(); !strings.Contains(view, "No favorites yet") {
+		t.Errorf("View() = %q after unstarring the last favorite, want none left", view)
+	}
+	if got := favorites(); len(got) != 0 {
+		t.Errorf("favorites = %v read back, want the dog unstarred", got)
+	}
+	if m = press(m, "*"); len(m.visible()) != 3 {
+		t.Errorf("visible = %v after * again, want all the art", m.visible())
+	}
+}
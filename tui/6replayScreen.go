@@ -0,0 +1,108 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sashabaranov/go-openai"
+)
+
+// exchange is a prompt and the response to it.
+type exchange struct {
+	prompt   string
+	response string
+}
+
+type replayModel struct {
+	exchanges   []exchange
+	index       int
+	senderStyle lipgloss.Style
+	width       int
+	height      int
+}
+
+// NewReplayModel steps through a markdown transcript saved from the chat one
+// exchange at a time.
+func NewReplayModel(path string) (replayModel, error) {
+	md, err := os.ReadFile(path)
+	if err != nil {
+		return replayModel{}, err
+	}
+	turns, err := parseTranscript(string(md))
+	if err != nil {
+		return replayModel{}, err
+	}
+	// Pair each response with the prompt before it, a turn without a partner
+	// gets an exchange of its own
+	exchanges := []exchange{}
+	for _, t := range turns {
+		if t.role == openai.ChatMessageRoleSystem {
+			// Instructions to the model aren't part of an exchange
+			continue
+		}
+		user := t.role == openai.ChatMessageRoleUser
+		if user || len(exchanges) == 0 || exchanges[len(exchanges)-1].response != "" {
+			exchanges = append(exchanges, exchange{})
+		}
+		if user {
+			exchanges[len(exchanges)-1].prompt = t.text
+		} else {
+			exchanges[len(exchanges)-1].response = t.text
+		}
+	}
+	if len(exchanges) == 0 {
+		// Such as a session saved after setting the style, before any prompt
+		return replayModel{}, errors.New("nothing to replay, the transcript has no prompts or responses")
+	}
+	return replayModel{
+		exchanges:   exchanges,
+		index:       0,
+		senderStyle: lipgloss.NewStyle().Foreground(lipgloss.Color("5")),
+		width:       80,
+		height:      10,
+	}, nil
+}
+
+func (m replayModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m replayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "ctrl+c", "q":
+			return m, tea.Quit
+		case "right", "down", "l", "j", " ":
+			if m.index < len(m.exchanges)-1 {
+				m.index++
+			}
+		case "left", "up", "h", "k":
+			if m.index > 0 {
+				m.index--
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m replayModel) View() string {
+	current := m.exchanges[m.index]
+	s := statusStyle.Render(fmt.Sprintf("Exchange %d/%d • ←/→ to step • q to quit", m.index+1, len(m.exchanges))) + "\n\n"
+	if current.prompt != "" {
+		s += m.senderStyle.Render(userLabel()+": ") + current.prompt + "\n"
+	}
+	if current.response != "" {
+		s += m.senderStyle.Render(assistantLabel()+": ") + current.response + "\n"
+	}
+	return s
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	db "github.com/ericulley/ascii/data"
+)
+
+var sheetSelectedStyle = previewStyle.BorderForeground(lipgloss.Color("5"))
+
+type sheetModel struct {
+	arts        []string
+	seed        *int
+	cursorIndex int
+	width       int
+	height      int
+	// The chat session to return to
+	chat *chatModel
+}
+
+// NewSheetModel lays out several pieces of art from one response side by side
+// so one of them can be picked to save.
+func NewSheetModel(arts []string, seed *int) sheetModel {
+	return sheetModel{
+		arts:        arts,
+		seed:        seed,
+		cursorIndex: 0,
+		width:       80,
+		height:      10,
+		chat:        nil,
+	}
+}
+
+func (m sheetModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m sheetModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+		case "esc":
+			if m.chat != nil {
+				return returnToChat(*m.chat)
+			}
+			return m, tea.Quit
+		case "left", "h", "up", "k":
+			if m.cursorIndex > 0 {
+				m.cursorIndex--
+			}
+		case "right", "l", "down", "j":
+			if m.cursorIndex < len(m.arts)-1 {
+				m.cursorIndex++
+			}
+		case "enter":
+			// Continue to the usual save question with the chosen art
+			record := db.AsciiRecord{Art: m.arts[m.cursorIndex], Seed: m.seed}
+			if m.chat != nil {
+				record = m.chat.artRecord(m.arts[m.cursorIndex], m.seed)
+			}
+			question := NewQuestionModel(record)
+			question.chat = m.chat
+			question.width = m.width
+			if m.chat != nil && m.chat.height > 0 {
+				question.height = m.chat.height
+			}
+			return question, nil
+		case "a":
+			// Save every variant under one base name, numbered in order
+			record := db.AsciiRecord{Seed: m.seed}
+			if m.chat != nil {
+				record = m.chat.artRecord("", m.seed)
+			}
+			prompt := NewPromptModel(record)
+			prompt.variants = m.arts
+			prompt.chat = m.chat
+			return prompt, textinput.Blink
+		}
+	}
+	return m, nil
+}
+
+func (m sheetModel) View() string {
+	rows := []string{}
+	row := []string{}
+	rowWidth := 0
+	for i, art := range m.arts {
+		style := previewStyle
+		if i == m.cursorIndex {
+			style = sheetSelectedStyle
+		}
+		cell := style.Render(fmt.Sprintf("%d\n", i+1) + stripFence(art))
+		// Wrap onto a new row when the terminal is full
+		if len(row) > 0 && rowWidth+lipgloss.Width(cell) > m.width {
+			rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+			row, rowWidth = nil, 0
+		}
+		row = append(row, cell)
+		rowWidth += lipgloss.Width(cell)
+	}
+	rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+	help := statusStyle.Render("←/→ to choose • enter to save • a to save all • esc to go back")
+	return lipgloss.JoinVertical(lipgloss.Left, rows...) + "\n\n" + help + "\n"
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestChatResponseWithVariants(t *testing.T) {
+	m := newTestChat(t, answering("```\na\n```\n```\nb\n```\n```\nc\n```"))
+	m.textarea.SetValue("three cats")
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	next, cmd = next.Update(awaitMsg[responseMsg](t, cmd))
+	next, cmd = next.Update(awaitMsg[artDrawnMsg](t, cmd))
+	next, _ = next.Update(awaitMsg[asciiMsg](t, cmd))
+	sheet, ok := next.(sheetModel)
+	if !ok {
+		t.Fatalf("moved on to %T, want the contact sheet", next)
+	}
+	if len(sheet.arts) != 3 || sheet.chat == nil {
+		t.Errorf("sheet has %d arts, chat %v, want 3 and the chat to return to", len(sheet.arts), sheet.chat)
+	}
+}
+
+func TestSheetView(t *testing.T) {
+	m := NewSheetModel([]string{"```\naaaa\n```", "```\nbbbb\n```", "```\ncccc\n```"}, nil)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	wide := ansi.Strip(next.View())
+	if lines := strings.Split(wide, "\n"); !strings.Contains(lines[1], "1") || !strings.Contains(lines[1], "3") {
+		t.Errorf("View() at 80 columns doesn't label the arts side by side:\n%s", wide)
+	}
+	// Each cell is 8 columns with its border and padding, two fit in 16
+	next, _ = next.Update(tea.WindowSizeMsg{Width: 16, Height: 20})
+	narrow := ansi.Strip(next.View())
+	if strings.Count(narrow, "aaaa") != 1 || strings.Index(narrow, "cccc") < strings.Index(narrow, "bbbb") {
+		t.Fatalf("View() didn't keep the arts in order:\n%s", narrow)
+	}
+	if lipgloss.Height(narrow) <= lipgloss.Height(wide) {
+		t.Errorf("View() didn't wrap at 16 columns:\n%s", narrow)
+	}
+}
+
+func TestSheetSelect(t *testing.T) {
+	testEnv(t)
+	arts := []string{"```\na\n```", "```\nb\n```"}
+	var next tea.Model = NewSheetModel(arts, nil)
+	for _, key := range []tea.KeyMsg{{Type: tea.KeyLeft}, {Type: tea.KeyRight}, {Type: tea.KeyRight}} {
+		next, _ = next.Update(key)
+	}
+	if got := next.(sheetModel).cursorIndex; got != 1 {
+		t.Fatalf("cursor = %d, want it to stop at the last art", got)
+	}
+	saved, _ := next.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if question, ok := saved.(questionModel); !ok || question.record.Art != arts[1] {
+		t.Errorf("enter moved on to %T, want the save question for the second art", saved)
+	}
+	all, _ := next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	if prompt, ok := all.(*promptModel); !ok || len(prompt.variants) != 2 {
+		t.Errorf("a moved on to %T, want the name prompt for every art", all)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type formModel struct {
+	labels     []string
+	fields     []textinput.Model
+	focusIndex int
+	// Why the prompt couldn't be sent, if it couldn't
+	problem string
+	// The chat session the prompt is sent from
+	chat *chatModel
+}
+
+// The fields of the form, in the order they're shown.
+const (
+	formSubject = iota
+	formStyle
+	formWidth
+	formComplexity
+)
+
+// NewFormModel asks for the parts of a prompt one field at a time, for when
+// writing one from scratch is hard to get right.
+func NewFormModel() formModel {
+	placeholders := []string{"a cat sleeping on a laptop", "cartoon, line art, blocks...", "60", "simple or detailed"}
+	fields := make([]textinput.Model, len(placeholders))
+	for i, placeholder := range placeholders {
+		fields[i] = textinput.New()
+		fields[i].Placeholder = placeholder
+		fields[i].Width = 60
+	}
+	fields[formWidth].Validate = func(s string) error {
+		if strings.Trim(s, "0123456789") != "" {
+			return fmt.Errorf("width must be a number")
+		}
+		return nil
+	}
+	fields[formSubject].Focus()
+	return formModel{
+		labels:     []string{"Subject", "Style", "Width", "Complexity"},
+		fields:     fields,
+		focusIndex: 0,
+		problem:    "",
+		chat:       nil,
+	}
+}
+
+func (m formModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m formModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			if m.chat != nil {
+				return returnToChat(*m.chat)
+			}
+			return m, tea.Quit
+		case "tab", "down":
+			return m, m.focus((m.focusIndex + 1) % len(m.fields))
+		case "shift+tab", "up":
+			return m, m.focus((m.focusIndex + len(m.fields) - 1) % len(m.fields))
+		case "enter":
+			if m.focusIndex < len(m.fields)-1 {
+				return m, m.focus(m.focusIndex + 1)
+			}
+			if strings.TrimSpace(m.fields[formSubject].Value()) == "" || m.chat == nil {
+				m.problem = "A subject is needed to make art of."
+				return m, m.focus(formSubject)
+			}
+			prompt := m.prompt()
+			if n := utf8.RuneCountInString(prompt); n > promptLimit {
+				// The same limit as typing it into the chat
+				m.problem = fmt.Sprintf("The prompt is %d characters, over the limit of %d. Shorten some of the fields.", n, promptLimit)
+				return m, nil
+			}
+			// Send it from the chat as if it had been typed there
+			chat := *m.chat
+			chat.ascii = nil
+			chat.textarea.SetValue(prompt)
+			autosave := chat.restartAutosave()
+			next, cmd := chat.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			return next, tea.Batch(cmd, textarea.Blink, autosave)
+		}
+	}
+	var cmd tea.Cmd
+	m.fields[m.focusIndex], cmd = m.fields[m.focusIndex].Update(msg)
+	return m, cmd
+}
+
+// prompt is what the fields assemble into so far.
+func (m formModel) prompt() string {
+	return assemblePrompt(
+		m.fields[formSubject].Value(),
+		m.fields[formStyle].Value(),
+		m.fields[formWidth].Value(),
+		m.fields[formComplexity].Value(),
+	)
+}
+
+// focus moves the cursor to the field at index.
+func (m *formModel) focus(index int) tea.Cmd {
+	m.fields[m.focusIndex].Blur()
+	m.focusIndex = index
+	return m.fields[index].Focus()
+}
+
+func (m formModel) View() string {
+	rows := []string{"Describe the art you'd like, then press enter on the last field to send it.", ""}
+	for i, field := range m.fields {
+		rows = append(rows, fmt.Sprintf("%-11s%s", m.labels[i]+":", field.View()))
+	}
+	// Counted the same way as the chat's counter
+	rows = append(rows, "", statusStyle.Render(fmt.Sprintf("prompt: %d/%d", utf8.RuneCountInString(m.prompt()), promptLimit)))
+	if m.problem != "" {
+		rows = append(rows, m.problem)
+	}
+	rows = append(rows, statusStyle.Render("tab/shift+tab to move between fields • esc to go back to the chat"))
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+// assemblePrompt turns the form's fields into a prompt, leaving out the ones
+// that weren't filled in.
+func assemblePrompt(subject, style, width, complexity string) string {
+	parts := []string{fmt.Sprintf("Draw ascii art of %s.", strings.TrimSpace(subject))}
+	if style = strings.TrimSpace(style); style != "" {
+		parts = append(parts, fmt.Sprintf("Use a %s style.", style))
+	}
+	if width = strings.TrimSpace(width); width != "" {
+		parts = append(parts, fmt.Sprintf("Keep it at most %s characters wide.", width))
+	}
+	if complexity = strings.TrimSpace(complexity); complexity != "" {
+		parts = append(parts, fmt.Sprintf("Make it %s.", complexity))
+	}
+	parts = append(parts, "Put the art in a code block.")
+	return strings.Join(parts, " ")
+}
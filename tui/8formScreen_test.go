@@ -0,0 +1,90 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestAssemblePrompt(t *testing.T) {
+	tests := []struct {
+		name                              string
+		subject, style, width, complexity string
+		want                              string
+	}{
+		{
+			name:    "subject only",
+			subject: "a cat",
+			want:    "Draw ascii art of a cat. Put the art in a code block.",
+		},
+		{
+			name:    "every field",
+			subject: " a cat ", style: "line art", width: "40", complexity: "simple",
+			want: "Draw ascii art of a cat. Use a line art style. Keep it at most 40 characters wide. Make it simple. Put the art in a code block.",
+		},
+		{
+			name:    "blank fields left out",
+			subject: "a cat", style: "  ", complexity: "detailed",
+			want: "Draw ascii art of a cat. Make it detailed. Put the art in a code block.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assemblePrompt(tt.subject, tt.style, tt.width, tt.complexity); got != tt.want {
+				t.Errorf("assemblePrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fillForm opens the form from chat and fills in the subject.
+func fillForm(chat chatModel, subject string) formModel {
+	form := NewFormModel()
+	form.chat = &chat
+	form.fields[formSubject].SetValue(subject)
+	form.focusIndex = len(form.fields) - 1
+	return form
+}
+
+func TestFormSends(t *testing.T) {
+	form := fillForm(newTestChat(t, answering("ok")), "a cat")
+	next, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	chat, ok := next.(chatModel)
+	if !ok {
+		t.Fatalf("Update(enter) = %T, want the chat", next)
+	}
+	if !chat.waiting || len(chat.history) != 1 || chat.history[0].Content != assemblePrompt("a cat", "", "", "") {
+		t.Errorf("waiting = %v, history = %+v, want the assembled prompt sent", chat.waiting, chat.history)
+	}
+	awaitMsg[responseMsg](t, cmd)
+}
+
+func TestFormPromptLimit(t *testing.T) {
+	// Emoji are one character each, like in the chat
+	subject := strings.Repeat("🐱", promptLimit)
+	form := fillForm(newTestChat(t, answering("ok")), subject)
+	if view := form.View(); !strings.Contains(view, "/280") {
+		t.Errorf("form doesn't count the prompt:\n%s", view)
+	}
+	next, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	got, ok := next.(formModel)
+	if !ok || cmd != nil {
+		t.Fatalf("Update(enter) over the limit = %T, %v, want the form to stay", next, cmd)
+	}
+	if !strings.Contains(got.problem, "over the limit of 280") || got.chat.waiting {
+		t.Errorf("problem = %q, waiting = %v, want the prompt held back", got.problem, got.chat.waiting)
+	}
+}
+
+func TestFormNotOpenedWhileWaiting(t *testing.T) {
+	m := newTestChat(t, answering("ok"))
+	m.waiting = true
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p"), Alt: true})
+	if _, ok := next.(chatModel); !ok {
+		t.Errorf("alt+p while waiting opened %T", next)
+	}
+}
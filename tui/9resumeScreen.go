@@ -0,0 +1,90 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+type resumeModel struct {
+	conversations []conversation
+	cursorIndex   int
+	err           error
+	// The chat session to return to
+	chat *chatModel
+}
+
+// NewResumeModel lists saved conversations so one can be picked up again.
+func NewResumeModel(conversations []conversation) resumeModel {
+	return resumeModel{
+		conversations: conversations,
+		cursorIndex:   0,
+		err:           nil,
+		chat:          nil,
+	}
+}
+
+func (m resumeModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m resumeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+		case "esc":
+			if m.chat != nil {
+				return returnToChat(*m.chat)
+			}
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursorIndex > 0 {
+				m.cursorIndex--
+			}
+		case "down", "j":
+			if m.cursorIndex < len(m.conversations)-1 {
+				m.cursorIndex++
+			}
+		case "enter":
+			if len(m.conversations) == 0 || m.chat == nil {
+				return m, nil
+			}
+			chat := m.chat.reset()
+			chat.width, chat.height = m.chat.width, m.chat.height
+			chat, err := chat.Resume(m.conversations[m.cursorIndex].id)
+			if err != nil {
+				m.err = err
+				return m, nil
+			}
+			return returnToChat(chat)
+		}
+	}
+	return m, nil
+}
+
+func (m resumeModel) View() string {
+	rows := []string{"Pick a conversation to carry on with.", ""}
+	if len(m.conversations) == 0 {
+		rows = append(rows, "There are no saved conversations in "+conversationDir()+" yet.")
+	}
+	for i, c := range m.conversations {
+		mark := "  "
+		if i == m.cursorIndex {
+			mark = "> "
+		}
+		rows = append(rows, fmt.Sprintf("%s%s  %s  %s", mark, c.id, c.updated.Format("Jan 2 15:04"), ansi.Truncate(strings.TrimSpace(c.title), 60, "…")))
+	}
+	rows = append(rows, "")
+	if m.err != nil {
+		rows = append(rows, "Oof: "+m.err.Error())
+	}
+	rows = append(rows, statusStyle.Render("↑/↓ to choose • enter to resume • esc to go back"))
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
@@ -0,0 +1,420 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+)
+
+const fence = "```"
+
+// extractArt returns the fenced code snippet in a response, fences included.
+// Art fenced with one of the other ASCII_FENCES markers, or failing that an
+// indented code block, is returned with ``` fences instead.
+func extractArt(content string) (string, bool) {
+	marker, start, end := "", -1, -1
+	for _, m := range fenceMarkers() {
+		// The marker that opens first wins
+		if s, e, ok := fenceBounds(content, m); ok && (start == -1 || s < start) {
+			marker, start, end = m, s, e
+		}
+	}
+	if start == -1 {
+		return indentedArt(content)
+	}
+	if marker == fence {
+		return content[start:end], true
+	}
+	inner := strings.TrimSuffix(content[start:end], marker)
+	inner = strings.TrimPrefix(inner, marker)
+	return fence + inner + fence, true
+}
+
+// extractArts returns every separately fenced block in a response, each with
+// ``` fences, for responses with several pieces of art in them.
+func extractArts(content string) []string {
+	arts := []string{}
+	var block []string
+	for _, line := range strings.Split(content, "\n") {
+		isFence := false
+		for _, marker := range fenceMarkers() {
+			if strings.HasPrefix(strings.TrimSpace(line), marker) {
+				isFence = true
+			}
+		}
+		switch {
+		case isFence && block == nil:
+			block = []string{}
+		case isFence:
+			arts = append(arts, fence+"\n"+strings.Join(block, "\n")+"\n"+fence)
+			block = nil
+		case block != nil:
+			block = append(block, line)
+		}
+	}
+	return arts
+}
+
+// fenceMarkers returns the markers art may be fenced with, set by a comma
+// separated ASCII_FENCES and defaulting to ```.
+func fenceMarkers() []string {
+	markers := []string{}
+	for _, m := range strings.Split(os.Getenv("ASCII_FENCES"), ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			markers = append(markers, m)
+		}
+	}
+	if len(markers) == 0 {
+		return []string{fence}
+	}
+	return markers
+}
+
+// fenceBounds finds where the first marker starts and the last one ends in a
+// single pass over content, without allocating. It jumps between bytes that
+// could start a marker, rather than checking every position. Overlapping
+// markers are matched the same way strings.Index and strings.LastIndex would.
+func fenceBounds(content string, marker string) (int, int, bool) {
+	first, last := -1, -1
+	for i := 0; i+len(marker) <= len(content); i++ {
+		next := strings.IndexByte(content[i:len(content)-len(marker)+1], marker[0])
+		if next == -1 {
+			break
+		}
+		i += next
+		if content[i:i+len(marker)] == marker {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first == -1 {
+		return 0, 0, false
+	}
+	return first, last + len(marker), true
+}
+
+// indentedArt finds the first markdown style indented code block of at least
+// two lines, for models that indent art instead of fencing it.
+func indentedArt(content string) (string, bool) {
+	block := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+			block = append(block, strings.TrimPrefix(strings.TrimPrefix(line, "\t"), "    "))
+			continue
+		}
+		if len(block) >= 2 {
+			break
+		}
+		block = block[:0]
+	}
+	if len(block) < 2 {
+		return "", false
+	}
+	return fence + "\n" + strings.Join(block, "\n") + "\n" + fence, true
+}
+
+// stitchArt joins a truncated response with its continuation so the art lines
+// keep their columns. Models don't always pick up cleanly, so a reopened fence
+// and a repeated last line are dropped from the continuation.
+func stitchArt(partial string, continuation string) string {
+	// The partial is still inside a code block, so a new opening fence in the
+	// continuation (along with any prose before it) is redundant
+	if strings.Count(partial, fence)%2 == 1 && strings.Count(continuation, fence) >= 2 {
+		start := strings.Index(continuation, fence)
+		if nl := strings.Index(continuation[start:], "\n"); nl != -1 {
+			continuation = continuation[start+nl+1:]
+		}
+	}
+
+	// Cut off at the end of a line, start the continuation on a new one
+	if strings.HasSuffix(partial, "\n") {
+		return partial + strings.TrimPrefix(continuation, "\n")
+	}
+
+	lastNl := strings.LastIndex(partial, "\n")
+	lastLine := partial[lastNl+1:]
+	firstLine, rest, _ := strings.Cut(continuation, "\n")
+	switch {
+	case lastLine != "" && strings.HasPrefix(firstLine, lastLine):
+		// The model repeated the unfinished line, keep its complete version
+		return partial[:lastNl+1] + continuation
+	case strings.TrimSpace(firstLine) == "" && rest != "":
+		// The model started over on a fresh line
+		return partial + "\n" + rest
+	default:
+		// Cut off mid-line, carry on from the same column
+		return partial + continuation
+	}
+}
+
+// stripFence removes the ``` lines wrapping a piece of art, leaving the art
+// itself untouched.
+func stripFence(art string) string {
+	if strings.HasPrefix(art, fence) {
+		// The opening fence may carry a language tag
+		if nl := strings.Index(art, "\n"); nl != -1 {
+			art = art[nl+1:]
+		} else {
+			art = strings.TrimPrefix(art, fence)
+		}
+	}
+	art = strings.TrimSuffix(art, fence)
+	return strings.TrimSuffix(art, "\n")
+}
+
+// plainArt returns art without its fences or any color and style escape
+// sequences, leaving only the characters that take up space, for measuring
+// and working with it cell by cell.
+func plainArt(art string) string {
+	return ansi.Strip(stripFence(art))
+}
+
+// trimTrailingSpace strips whitespace from the end of every line of art,
+// leaving the spacing inside each line alone.
+func trimTrailingSpace(art string) string {
+	lines := strings.Split(art, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trimBlankLines drops the empty lines models pad art with above and below
+// it, inside its fences. Blank lines within the art are kept.
+func trimBlankLines(art string) string {
+	lines := strings.Split(art, "\n")
+	top, bottom := "", ""
+	if len(lines) > 1 && strings.HasPrefix(lines[0], fence) && strings.TrimSpace(lines[len(lines)-1]) == fence {
+		top, bottom = lines[0], lines[len(lines)-1]
+		lines = lines[1 : len(lines)-1]
+	}
+	blank := func(line string) bool {
+		return strings.TrimSpace(ansi.Strip(line)) == ""
+	}
+	for len(lines) > 0 && blank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && blank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	if top == "" {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(append(append([]string{top}, lines...), bottom), "\n")
+}
+
+// artSize returns the width and height of art in terminal cells, ignoring its
+// fences.
+func artSize(art string) (int, int) {
+	art = plainArt(art)
+	return lipgloss.Width(art), lipgloss.Height(art)
+}
+
+// fitFactor returns the smallest integer downscale that fits art within the
+// given width and height.
+func fitFactor(art string, width int, height int) int {
+	w, h := artSize(art)
+	factor := 1
+	if width < 1 || height < 1 {
+		return factor
+	}
+	for (w+factor-1)/factor > width || (h+factor-1)/factor > height {
+		factor++
+	}
+	return factor
+}
+
+// scaleArt shrinks art by keeping every factor-th row and column, using the
+// same factor both ways to preserve its aspect ratio. Columns are counted in
+// terminal cells, so wide characters take up two and combining characters
+// stay with the character they modify. Colors are dropped, since sampling
+// would cut their escape sequences apart.
+func scaleArt(art string, factor int) string {
+	if factor <= 1 {
+		return art
+	}
+	lines := strings.Split(plainArt(art), "\n")
+	scaled := []string{}
+	for i := 0; i < len(lines); i += factor {
+		var line strings.Builder
+		col, kept := 0, false
+		for _, r := range lines[i] {
+			w := runewidth.RuneWidth(r)
+			if w == 0 {
+				if kept {
+					line.WriteRune(r)
+				}
+				continue
+			}
+			// Keep the character covering each sampled column
+			next := (col + factor - 1) / factor * factor
+			kept = next < col+w
+			if kept {
+				line.WriteRune(r)
+			}
+			col += w
+		}
+		scaled = append(scaled, line.String())
+	}
+	return fence + "\n" + strings.Join(scaled, "\n") + "\n" + fence
+}
+
+// numberLines prefixes each line of art with its right aligned line number,
+// dimmed, for display only. The gutter is as wide as the largest number.
+func numberLines(art string) string {
+	lines := strings.Split(stripFence(art), "\n")
+	gutter := len(strconv.Itoa(len(lines)))
+	for i, line := range lines {
+		lines[i] = statusStyle.Render(fmt.Sprintf("%*d ", gutter, i+1)) + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// region is a rectangle of art cells, rows and columns counted from zero with
+// both ends included.
+type region struct {
+	top, left, bottom, right int
+}
+
+// newRegion returns the region with corners at the two given cells, in either
+// order.
+func newRegion(row1, col1, row2, col2 int) region {
+	return region{
+		top:    min(row1, row2),
+		left:   min(col1, col2),
+		bottom: max(row1, row2),
+		right:  max(col1, col2),
+	}
+}
+
+func (r region) width() int  { return r.right - r.left + 1 }
+func (r region) height() int { return r.bottom - r.top + 1 }
+
+// contains reports whether the cell at row and col is inside the region.
+func (r region) contains(row, col int) bool {
+	return row >= r.top && row <= r.bottom && col >= r.left && col <= r.right
+}
+
+// artCells splits art, without its fences, into lines of characters padded
+// with spaces to at least width, so every column in range can be indexed.
+// Columns are counted in characters rather than terminal cells, and colors
+// are dropped so they don't count as characters.
+func artCells(art string, width int) [][]rune {
+	lines := strings.Split(plainArt(art), "\n")
+	cells := make([][]rune, len(lines))
+	for i, line := range lines {
+		cells[i] = []rune(line)
+		for len(cells[i]) < width {
+			cells[i] = append(cells[i], ' ')
+		}
+	}
+	return cells
+}
+
+// cropRegion returns the part of art inside r. Parts of r beyond the art come
+// back as spaces.
+func cropRegion(art string, r region) string {
+	cells := artCells(art, r.right+1)
+	lines := []string{}
+	for row := r.top; row <= r.bottom; row++ {
+		if row >= len(cells) {
+			lines = append(lines, strings.Repeat(" ", r.width()))
+			continue
+		}
+		lines = append(lines, string(cells[row][r.left:r.right+1]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// spliceRegion replaces the part of art inside r with patch and returns the
+// fenced result. The patch is cut or padded with spaces to exactly the size of
+// r so the art around it stays aligned, and the art grows to fit r if needed.
+// Spaces only added to make room are trimmed again.
+func spliceRegion(art string, r region, patch string) string {
+	cells := artCells(art, r.right+1)
+	// How long each line was, so only the padding gets trimmed back off
+	lengths := []int{}
+	for _, line := range strings.Split(plainArt(art), "\n") {
+		lengths = append(lengths, len([]rune(line)))
+	}
+	for len(cells) <= r.bottom {
+		lengths = append(lengths, 0)
+		cells = append(cells, []rune(strings.Repeat(" ", r.right+1)))
+	}
+	patchLines := strings.Split(strings.Trim(plainArt(patch), "\n"), "\n")
+	for row := r.top; row <= r.bottom; row++ {
+		line := []rune{}
+		if i := row - r.top; i < len(patchLines) {
+			line = []rune(patchLines[i])
+		}
+		for col := r.left; col <= r.right; col++ {
+			cell := ' '
+			if i := col - r.left; i < len(line) {
+				cell = line[i]
+			}
+			cells[row][col] = cell
+		}
+	}
+	lines := make([]string, len(cells))
+	for i, line := range cells {
+		lines[i] = string(line[:lengths[i]]) + strings.TrimRight(string(line[lengths[i]:]), " ")
+	}
+	return fence + "\n" + strings.Join(lines, "\n") + "\n" + fence
+}
+
+// longLine reports whether art came back as a single line too wide for width,
+// as models sometimes send it without its newlines.
+func longLine(art string, width int) bool {
+	w, h := artSize(art)
+	return h == 1 && width > 0 && w > width
+}
+
+// wrapArt breaks art's lines every width cells, keeping its fences.
+func wrapArt(art string, width int) string {
+	wrapped := ansi.Hardwrap(stripFence(art), max(1, width), true)
+	if strings.HasPrefix(art, fence) {
+		return fence + "\n" + wrapped + "\n" + fence
+	}
+	return wrapped
+}
+
+// scrollArt shows the width columns of a single line of art starting at
+// offset, for looking along art too long to fit.
+func scrollArt(art string, offset int, width int) string {
+	line := artCells(art, 0)[0]
+	offset = min(max(0, offset), len(line))
+	return string(line[offset:min(len(line), offset+width)])
+}
+
+// escapeStyle sets the escapes shown by showEscapes apart from the art.
+var escapeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+
+// showEscapes makes the control characters in art visible in caret notation,
+// e.g. ESC as ^[, so color sequences can be read instead of acted on. Only
+// for display, the art itself is left alone.
+func showEscapes(art string) string {
+	var b strings.Builder
+	for _, r := range art {
+		switch {
+		case r == '\n':
+			b.WriteRune(r)
+		case r < 0x20:
+			b.WriteString(escapeStyle.Render("^" + string(r+0x40)))
+		case r == 0x7f:
+			b.WriteString(escapeStyle.Render("^?"))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
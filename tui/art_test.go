@@ -0,0 +1,372 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractArt(t *testing.T) {
+	tests := []struct {
+		name    string
+		fences  string
+		content string
+		want    string
+		wantOK  bool
+	}{
+		{name: "none", content: "no art here", wantOK: false},
+		{name: "fenced", content: "Here:\n```\n/\\_/\\\n```\nEnjoy!", want: "```\n/\\_/\\\n```", wantOK: true},
+		{name: "language tag", content: "```text\n(o.o)\n```", want: "```text\n(o.o)\n```", wantOK: true},
+		{name: "first to last", content: "```\na\n```\nand\n```\nb\n```", want: "```\na\n```\nand\n```\nb\n```", wantOK: true},
+		{name: "unclosed", content: "```\nhalf", want: "```", wantOK: true},
+		{name: "indented", content: "Art:\n    /\\\n    \\/\nDone", want: "```\n/\\\n\\/\n```", wantOK: true},
+		{name: "one indented line", content: "Art:\n    /\\\nDone", wantOK: false},
+		{name: "other marker", fences: "~~~", content: "~~~\n<o>\n~~~", want: "```\n<o>\n```", wantOK: true},
+		{name: "first marker wins", fences: "~~~,```", content: "```\na\n```\n~~~\nb\n~~~", want: "```\na\n```", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASCII_FENCES", tt.fences)
+			got, ok := extractArt(tt.content)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("extractArt(%q) = %q, %v, want %q, %v", tt.content, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestStripFence(t *testing.T) {
+	tests := []struct {
+		art  string
+		want string
+	}{
+		{art: "```\n/\\\n```", want: "/\\"},
+		{art: "```text\n/\\\n```", want: "/\\"},
+		{art: "/\\", want: "/\\"},
+		{art: "```\n  x  \n\n```", want: "  x  \n"},
+	}
+	for _, tt := range tests {
+		if got := stripFence(tt.art); got != tt.want {
+			t.Errorf("stripFence(%q) = %q, want %q", tt.art, got, tt.want)
+		}
+	}
+}
+
+// indexBounds is what fenceBounds replaced, finding the markers with
+// strings.Index and strings.LastIndex.
+func indexBounds(content string, marker string) (int, int, bool) {
+	start := strings.Index(content, marker)
+	if start == -1 {
+		return 0, 0, false
+	}
+	return start, strings.LastIndex(content, marker) + len(marker), true
+}
+
+func TestFenceBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		marker  string
+	}{
+		{name: "empty", content: "", marker: fence},
+		{name: "no fence", content: "just words", marker: fence},
+		{name: "one fence", content: "```", marker: fence},
+		{name: "opened only", content: "art:\n```\n/\\", marker: fence},
+		{name: "fenced", content: "art:\n```\n/\\\n```\nbye", marker: fence},
+		{name: "two blocks", content: "```\na\n```\n```\nb\n```", marker: fence},
+		{name: "four backticks", content: "````", marker: fence},
+		{name: "five backticks", content: "x`````y", marker: fence},
+		{name: "backticks around art", content: "````\nart\n````", marker: fence},
+		{name: "marker longer than content", content: "``", marker: fence},
+		{name: "other marker", content: "~~~\n<o>\n~~~~", marker: "~~~"},
+		{name: "shared first byte", content: "`~`~~~`~", marker: "`~"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := fenceBounds(tt.content, tt.marker)
+			wantStart, wantEnd, wantOK := indexBounds(tt.content, tt.marker)
+			if start != wantStart || end != wantEnd || ok != wantOK {
+				t.Errorf("fenceBounds(%q, %q) = %d, %d, %v, want %d, %d, %v", tt.content, tt.marker, start, end, ok, wantStart, wantEnd, wantOK)
+			}
+		})
+	}
+}
+
+func TestFenceBoundsShortStrings(t *testing.T) {
+	// Every string of up to 8 backticks, newlines and letters
+	var check func(content string)
+	check = func(content string) {
+		start, end, ok := fenceBounds(content, fence)
+		wantStart, wantEnd, wantOK := indexBounds(content, fence)
+		if start != wantStart || end != wantEnd || ok != wantOK {
+			t.Fatalf("fenceBounds(%q) = %d, %d, %v, want %d, %d, %v", content, start, end, ok, wantStart, wantEnd, wantOK)
+		}
+		if len(content) < 8 {
+			for _, c := range []string{"`", "\n", "a"} {
+				check(content + c)
+			}
+		}
+	}
+	check("")
+}
+
+// benchmarkResponse is a long response with art in the middle.
+var benchmarkResponse = strings.Repeat("Some words before the art. ", 200) + "\n```\n" + strings.Repeat("/\\/\\/\\ `` \\/\\/\\/\n", 100) + "```\n" + strings.Repeat("And some after. ", 200)
+
+func BenchmarkFenceBounds(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		fenceBounds(benchmarkResponse, fence)
+	}
+}
+
+func BenchmarkIndexBounds(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		indexBounds(benchmarkResponse, fence)
+	}
+}
+
+func TestArtSizeWideCharacters(t *testing.T) {
+	tests := []struct {
+		name          string
+		art           string
+		width, height int
+	}{
+		{"ascii", "```\n/\\_/\\\n( o.o )\n```", 7, 2},
+		{"box drawing", "┌──┐\n└──┘", 4, 2},
+		{"wide", "```\n日本\nab\n```", 4, 2},
+		{"emoji", "🐱🐱", 4, 1},
+		{"combining", "e\u0301e\u0301", 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w, h := artSize(tt.art); w != tt.width || h != tt.height {
+				t.Errorf("artSize() = %d, %d, want %d, %d", w, h, tt.width, tt.height)
+			}
+		})
+	}
+}
+
+func TestScaleArtWideCharacters(t *testing.T) {
+	tests := []struct {
+		name   string
+		art    string
+		factor int
+		want   string
+	}{
+		{"ascii", "abcdef\nghijkl\nmnopqr", 2, "ace\nmoq"},
+		{"box drawing", "┌────┐", 2, "┌──"},
+		{"wide", "日本語漢", 2, "日本語漢"},
+		{"wide across a column", "a日b", 2, "a日"},
+		{"wide skipped", "日本語漢", 3, "日本漢"},
+		{"combining", "e\u0301xe\u0301", 2, "e\u0301e\u0301"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scaleArt(tt.art, tt.factor); got != fence+"\n"+tt.want+"\n"+fence {
+				t.Errorf("scaleArt(%q, %d) = %q, want %q fenced", tt.art, tt.factor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitFactorWideCharacters(t *testing.T) {
+	if got := fitFactor("日本語漢", 4, 10); got != 2 {
+		t.Errorf("fitFactor() = %d for 8 cells in 4, want 2", got)
+	}
+	if got := fitFactor("🐱🐱", 4, 10); got != 1 {
+		t.Errorf("fitFactor() = %d for 4 cells in 4, want 1", got)
+	}
+}
+
+func TestFenceMarkers(t *testing.T) {
+	tests := []struct {
+		fences string
+		want   []string
+	}{
+		{fences: "", want: []string{"```"}},
+		{fences: " , ", want: []string{"```"}},
+		{fences: "~~~", want: []string{"~~~"}},
+		{fences: " ~~~ , ``` ", want: []string{"~~~", "```"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("ASCII_FENCES", tt.fences)
+		if got := fenceMarkers(); !slices.Equal(got, tt.want) {
+			t.Errorf("fenceMarkers() = %q with ASCII_FENCES=%q, want %q", got, tt.fences, tt.want)
+		}
+	}
+}
+
+func TestExtractArtTildesAndIndents(t *testing.T) {
+	tests := []struct {
+		name    string
+		fences  string
+		content string
+		want    string
+		wantOK  bool
+	}{
+		{name: "tildes by default", content: "~~~\n<o>\n~~~", wantOK: false},
+		{name: "tildes with language tag", fences: "~~~", content: "~~~text\n<o>\n~~~", want: "```text\n<o>\n```", wantOK: true},
+		{name: "tildes and backticks", fences: "```,~~~", content: "Art:\n~~~\n<o>\n~~~", want: "```\n<o>\n```", wantOK: true},
+		{name: "tab indented", content: "Art:\n\t/\\\n\t\\/", want: "```\n/\\\n\\/\n```", wantOK: true},
+		{name: "indents kept past the block's", content: "    /\\\n      ||\n    \\/", want: "```\n/\\\n  ||\n\\/\n```", wantOK: true},
+		{name: "first indented block", content: "    a\n    b\ntext\n    c\n    d", want: "```\na\nb\n```", wantOK: true},
+		{name: "fence before indent", content: "    a\n    b\n```\nc\n```", want: "```\nc\n```", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASCII_FENCES", tt.fences)
+			got, ok := extractArt(tt.content)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("extractArt(%q) = %q, %v, want %q, %v", tt.content, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestExtractArts(t *testing.T) {
+	tests := []struct {
+		name    string
+		fences  string
+		content string
+		want    []string
+	}{
+		{name: "none", content: "no art here", want: []string{}},
+		{name: "one", content: "Here:\n```\na\n```", want: []string{"```\na\n```"}},
+		{
+			name:    "three variations",
+			content: "1.\n```\na\n```\n2.\n```text\nb\nb\n```\n3.\n```\nc\n```\nEnjoy!",
+			want:    []string{"```\na\n```", "```\nb\nb\n```", "```\nc\n```"},
+		},
+		{name: "unclosed last", content: "```\na\n```\n```\nb", want: []string{"```\na\n```"}},
+		{name: "indented fences", content: "  ```\n  a\n  ```", want: []string{"```\n  a\n```"}},
+		{name: "other markers", fences: "~~~,```", content: "~~~\na\n~~~\n```\nb\n```", want: []string{"```\na\n```", "```\nb\n```"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASCII_FENCES", tt.fences)
+			if got := extractArts(tt.content); !slices.Equal(got, tt.want) {
+				t.Errorf("extractArts(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorizedArt(t *testing.T) {
+	red := func(s string) string { return "\x1b[31m" + s + "\x1b[0m" }
+	art := fence + "\n" + red("/\\_/\\") + "\n" + red("( o.o )") + "\n" + fence
+	if got := plainArt(art); got != "/\\_/\\\n( o.o )" {
+		t.Errorf("plainArt() = %q, want the escape sequences dropped", got)
+	}
+	if w, h := artSize(art); w != 7 || h != 2 {
+		t.Errorf("artSize() = %d, %d, want 7, 2", w, h)
+	}
+	if got := fitFactor(art, 7, 2); got != 1 {
+		t.Errorf("fitFactor() = %d for art that fits, want 1", got)
+	}
+	if got := scaleArt(art, 2); got != fence+"\n/_\\\n"+fence {
+		t.Errorf("scaleArt() = %q, want the plain characters sampled", got)
+	}
+	if longLine(fence+"\n"+red(strings.Repeat("#", 10))+"\n"+fence, 10) {
+		t.Error("longLine() counted escape sequences as columns")
+	}
+	padded := fence + "\n" + red("   ") + "\n" + red("#") + "\n\x1b[0m\n" + fence
+	if got := trimBlankLines(padded); got != fence+"\n"+red("#")+"\n"+fence {
+		t.Errorf("trimBlankLines() = %q, want colored blank lines dropped", got)
+	}
+}
+
+func TestTrimBlankLines(t *testing.T) {
+	tests := []struct {
+		name string
+		art  string
+		want string
+	}{
+		{name: "padded inside fences", art: "```\n\n  \n /\\\n/  \\\n\n\t\n```", want: "```\n /\\\n/  \\\n```"},
+		{name: "fence with a language", art: "```text\n\n(o.o)\n\n```", want: "```text\n(o.o)\n```"},
+		{name: "without fences", art: "\n\n(o.o)\n\n", want: "(o.o)"},
+		{name: "blank lines inside kept", art: "```\n\n  *\n\n\n *** \n\n```", want: "```\n  *\n\n\n *** \n```"},
+		{name: "leading spaces kept", art: "```\n\n    /\\\n   /  \\\n```", want: "```\n    /\\\n   /  \\\n```"},
+		{name: "colored blank line", art: "```\n\x1b[31m  \x1b[0m\n(o.o)\n```", want: "```\n(o.o)\n```"},
+		{name: "nothing to trim", art: "```\n(o.o)\n```", want: "```\n(o.o)\n```"},
+		{name: "only blank lines", art: "```\n\n\n```", want: "```\n```"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimBlankLines(tt.art); got != tt.want {
+				t.Errorf("trimBlankLines(%q) = %q, want %q", tt.art, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatTrimsBlankLines(t *testing.T) {
+	for _, tt := range []struct {
+		trim string
+		want string
+	}{
+		{trim: "", want: "```\n /\\\n\n/__\\\n```"},
+		{trim: "false", want: "```\n\n /\\\n\n/__\\\n\n```"},
+	} {
+		t.Run("trim="+tt.trim, func(t *testing.T) {
+			t.Setenv("ASCII_TRIM_BLANK_LINES", tt.trim)
+			m := newTestChat(t, answering("Here:\n```\n\n /\\\n\n/__\\\n\n```"))
+			m = sendThrough(t, m, "a tent")
+			if m.ascii == nil || m.ascii.art != tt.want {
+				t.Fatalf("art = %+v, want %q", m.ascii, tt.want)
+			}
+			if latest := m.arts[len(m.arts)-1]; latest != tt.want {
+				t.Errorf("kept art %q, want %q", latest, tt.want)
+			}
+		})
+	}
+}
+
+func TestStitchArt(t *testing.T) {
+	tests := []struct {
+		name         string
+		partial      string
+		continuation string
+		want         string
+	}{
+		{name: "reopened fence", partial: "Here:\n```\n /\\\n", continuation: "Continuing:\n```\n/__\\\n```", want: "Here:\n```\n /\\\n/__\\\n```"},
+		{name: "reopened fence with language tag", partial: "```\n /\\\n", continuation: "```text\n/__\\\n```", want: "```\n /\\\n/__\\\n```"},
+		{name: "fence closed before the cut", partial: "```\n/\\\n```\nThat's", continuation: " a tent.\n```\n/\\\n```", want: "```\n/\\\n```\nThat's a tent.\n```\n/\\\n```"},
+		{name: "repeated last line", partial: "```\n /\\\n/_", continuation: "/__\\\n```", want: "```\n /\\\n/__\\\n```"},
+		{name: "fresh line", partial: "```\n /\\", continuation: "\n/__\\\n```", want: "```\n /\\\n/__\\\n```"},
+		{name: "fresh line after blank spaces", partial: "```\n /\\", continuation: "  \n/__\\\n```", want: "```\n /\\\n/__\\\n```"},
+		{name: "cut at the end of a line", partial: "```\n /\\\n", continuation: "\n/__\\\n```", want: "```\n /\\\n/__\\\n```"},
+		{name: "mid-line", partial: "```\n /\\\n/_", continuation: "_\\\n```", want: "```\n /\\\n/__\\\n```"},
+		{name: "mid-line keeps the columns", partial: "```\n| |", continuation: " |\n```", want: "```\n| | |\n```"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stitchArt(tt.partial, tt.continuation); got != tt.want {
+				t.Errorf("stitchArt(%q, %q) = %q, want %q", tt.partial, tt.continuation, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimTrailingSpace(t *testing.T) {
+	tests := []struct {
+		name string
+		art  string
+		want string
+	}{
+		{name: "trailing spaces", art: " /\\   \n/__\\ ", want: " /\\\n/__\\"},
+		{name: "tabs and carriage returns", art: "/\\\t\r\n\\/\r", want: "/\\\n\\/"},
+		{name: "leading and inner spaces kept", art: "   o   o\n    \\_/", want: "   o   o\n    \\_/"},
+		{name: "blank lines kept", art: "o\n   \no", want: "o\n\no"},
+		{name: "fences", art: "```   \n o \n```", want: "```\n o\n```"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimTrailingSpace(tt.art); got != tt.want {
+				t.Errorf("trimTrailingSpace(%q) = %q, want %q", tt.art, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// branch is a snapshot of a conversation that can be switched back to.
+type branch struct {
+	name     string
+	messages []string
+	history  []openai.ChatCompletionMessage
+	arts     []string
+	// The dividers and the last prompt, kept with the history their positions
+	// are in
+	dividers     []int
+	lastPrompt   string
+	lastPromptAt int
+}
+
+// snapshot copies the current conversation so later messages don't change it.
+func (m chatModel) snapshot(name string) branch {
+	return branch{
+		name:         name,
+		messages:     slices.Clone(m.messages),
+		history:      slices.Clone(m.history),
+		arts:         slices.Clone(m.arts),
+		dividers:     slices.Clone(m.dividers),
+		lastPrompt:   m.lastPrompt,
+		lastPromptAt: m.lastPromptAt,
+	}
+}
+
+// restore switches the conversation to a branch.
+func (m *chatModel) restore(b branch) {
+	m.messages = slices.Clone(b.messages)
+	m.history = slices.Clone(b.history)
+	m.arts = slices.Clone(b.arts)
+	m.artIndex = max(0, len(m.arts)-1)
+	m.previewing = false
+	m.truncated = false
+	m.partial = ""
+	m.dividers = slices.Clone(b.dividers)
+	m.lastPrompt = b.lastPrompt
+	m.lastPromptAt = b.lastPromptAt
+	m.selected = -1
+}
+
+// branchOff saves the conversation under a new branch and switches to it, so
+// the current state can be returned to later.
+func (m *chatModel) branchOff() {
+	if len(m.branches) == 0 {
+		m.branches = append(m.branches, m.snapshot("main"))
+	} else {
+		m.branches[m.branchIndex] = m.snapshot(m.branches[m.branchIndex].name)
+	}
+	m.branches = append(m.branches, m.snapshot(fmt.Sprintf("branch-%d", len(m.branches))))
+	m.branchIndex = len(m.branches) - 1
+	m.status = m.branchStatus()
+}
+
+// switchBranch saves the current branch and moves on to the next one.
+func (m *chatModel) switchBranch() {
+	if len(m.branches) < 2 {
+		return
+	}
+	m.branches[m.branchIndex] = m.snapshot(m.branches[m.branchIndex].name)
+	m.branchIndex = (m.branchIndex + 1) % len(m.branches)
+	m.restore(m.branches[m.branchIndex])
+	m.status = m.branchStatus()
+	m.refresh()
+}
+
+func (m chatModel) branchStatus() string {
+	return fmt.Sprintf("on %s (%d/%d)", m.branches[m.branchIndex].name, m.branchIndex+1, len(m.branches))
+}
@@ -0,0 +1,66 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestBranches(t *testing.T) {
+	client := answering("meow")
+	m := sendThrough(t, newTestChat(t, client), "a cat")
+	press := func(msg tea.KeyMsg) {
+		next, _ := m.Update(msg)
+		m = next.(chatModel)
+	}
+	branchOff := tea.KeyMsg{Type: tea.KeyCtrlO}
+	switchBranch := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o"), Alt: true}
+	divider := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("-"), Alt: true}
+
+	// Switching with a single branch does nothing
+	press(switchBranch)
+	if len(m.branches) != 0 || len(m.history) != 2 {
+		t.Fatalf("branches = %d, history = %d, want nothing changed", len(m.branches), len(m.history))
+	}
+
+	press(branchOff)
+	if len(m.branches) != 2 || m.branchIndex != 1 || m.status != "on branch-1 (2/2)" {
+		t.Fatalf("branches = %d at %d, status %q, want a new branch switched to", len(m.branches), m.branchIndex, m.status)
+	}
+	press(divider)
+	m = sendThrough(t, m, "a dog")
+	if len(m.history) != 4 || !slices.Equal(m.dividers, []int{2}) || m.lastPromptAt != 2 {
+		t.Fatalf("history = %d, dividers = %v, last prompt at %d on the branch", len(m.history), m.dividers, m.lastPromptAt)
+	}
+	m.selected = 3
+
+	press(switchBranch)
+	if m.status != "on main (1/2)" || len(m.history) != 2 || len(m.arts) != 0 {
+		t.Fatalf("status %q, history = %d, want main as it was branched off", m.status, len(m.history))
+	}
+	if len(m.dividers) != 0 || m.lastPrompt != "a cat" || m.lastPromptAt != 0 || m.selected != -1 {
+		t.Errorf("dividers = %v, last prompt %q at %d, selected %d, want main's own", m.dividers, m.lastPrompt, m.lastPromptAt, m.selected)
+	}
+	if md := transcriptMarkdown(m.history, m.dividers); strings.Contains(md, markdownRule) {
+		t.Errorf("main's transcript has the other branch's divider:\n%s", md)
+	}
+	if strings.Contains(m.viewport.View(), "a dog") {
+		t.Errorf("main shows the other branch's messages:\n%s", m.viewport.View())
+	}
+
+	press(switchBranch)
+	if m.status != "on branch-1 (2/2)" || len(m.history) != 4 {
+		t.Fatalf("status %q, history = %d, want the branch back", m.status, len(m.history))
+	}
+	if !slices.Equal(m.dividers, []int{2}) || m.lastPrompt != "a dog" || m.lastPromptAt != 2 {
+		t.Errorf("dividers = %v, last prompt %q at %d, want the branch's own", m.dividers, m.lastPrompt, m.lastPromptAt)
+	}
+	if md := transcriptMarkdown(m.history, m.dividers); !strings.Contains(md, "meow\n\n---\n\n## user\n\na dog") {
+		t.Errorf("the divider isn't where it was put in:\n%s", md)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// capabilities are the request features a model supports.
+type capabilities struct {
+	stream         bool
+	tools          bool
+	multipleN      bool
+	seed           bool
+	jsonMode       bool
+	systemMessages bool
+}
+
+// modelCapabilities lists what each known model supports. Requests for models
+// not listed here are sent as they are.
+var modelCapabilities = map[string]capabilities{
+	"gpt-4o-mini":   {stream: true, tools: true, multipleN: true, seed: true, jsonMode: true, systemMessages: true},
+	"gpt-4o":        {stream: true, tools: true, multipleN: true, seed: true, jsonMode: true, systemMessages: true},
+	"gpt-4-turbo":   {stream: true, tools: true, multipleN: true, seed: true, jsonMode: true, systemMessages: true},
+	"gpt-4":         {stream: true, tools: true, multipleN: true, seed: true, jsonMode: false, systemMessages: true},
+	"gpt-3.5-turbo": {stream: true, tools: true, multipleN: true, seed: true, jsonMode: true, systemMessages: true},
+	"o1-mini":       {stream: false, tools: false, multipleN: false, seed: true, jsonMode: false, systemMessages: false},
+	"o1-preview":    {stream: false, tools: false, multipleN: false, seed: true, jsonMode: false, systemMessages: false},
+}
+
+// checkCapabilities drops anything from the request its model doesn't
+// support, returning a warning for each change so the user knows why it was
+// ignored instead of getting an api error.
+func checkCapabilities(req *openai.ChatCompletionRequest) []string {
+	caps, ok := modelCapabilities[req.Model]
+	if !ok {
+		return nil
+	}
+	warnings := []string{}
+	unsupported := func(feature string) {
+		warnings = append(warnings, fmt.Sprintf("%s doesn't support %s, sending without it", req.Model, feature))
+	}
+	if req.Stream && !caps.stream {
+		req.Stream = false
+		unsupported("streaming")
+	}
+	if len(req.Tools) > 0 && !caps.tools {
+		req.Tools = nil
+		req.ToolChoice = nil
+		unsupported("tools")
+	}
+	if req.N > 1 && !caps.multipleN {
+		req.N = 0
+		unsupported("multiple choices")
+	}
+	if req.Seed != nil && !caps.seed {
+		req.Seed = nil
+		unsupported("seeds")
+	}
+	if req.ResponseFormat != nil && req.ResponseFormat.Type != openai.ChatCompletionResponseFormatTypeText && !caps.jsonMode {
+		req.ResponseFormat = nil
+		unsupported("JSON responses")
+	}
+	if !caps.systemMessages {
+		if messages, folded := foldSystemMessages(req.Messages); folded {
+			req.Messages = messages
+			// Instructions like the style still matter, so they're kept
+			warnings = append(warnings, fmt.Sprintf("%s doesn't support system messages, sending them with the prompt", req.Model))
+		}
+	}
+	return warnings
+}
+
+// foldSystemMessages moves the system messages in messages, such as the style
+// hint, to the front of the last user message, for models that turn system
+// messages away. Without a user message they're sent as one.
+func foldSystemMessages(messages []openai.ChatCompletionMessage) ([]openai.ChatCompletionMessage, bool) {
+	instructions := []string{}
+	rest := []openai.ChatCompletionMessage{}
+	for _, message := range messages {
+		if message.Role == openai.ChatMessageRoleSystem {
+			instructions = append(instructions, message.Content)
+		} else {
+			rest = append(rest, message)
+		}
+	}
+	if len(instructions) == 0 {
+		return messages, false
+	}
+	folded := strings.Join(instructions, "\n\n")
+	for i := len(rest) - 1; i >= 0; i-- {
+		if rest[i].Role == openai.ChatMessageRoleUser {
+			rest[i].Content = folded + "\n\n" + rest[i].Content
+			return rest, true
+		}
+	}
+	return append(rest, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: folded}), true
+}
@@ -0,0 +1,153 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestCheckCapabilities(t *testing.T) {
+	seed := 7
+	full := func(model string) openai.ChatCompletionRequest {
+		return openai.ChatCompletionRequest{
+			Model:          model,
+			Stream:         true,
+			N:              3,
+			Seed:           &seed,
+			Tools:          []openai.Tool{{Type: openai.ToolTypeFunction}},
+			ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+		}
+	}
+	tests := []struct {
+		model    string
+		want     openai.ChatCompletionRequest
+		warnings []string
+	}{
+		{model: "gpt-4o", want: full("gpt-4o"), warnings: []string{}},
+		{
+			model: "gpt-4",
+			want: func() openai.ChatCompletionRequest {
+				req := full("gpt-4")
+				req.ResponseFormat = nil
+				return req
+			}(),
+			warnings: []string{"JSON responses"},
+		},
+		{
+			model:    "o1-mini",
+			want:     openai.ChatCompletionRequest{Model: "o1-mini", Seed: &seed},
+			warnings: []string{"streaming", "tools", "multiple choices", "JSON responses"},
+		},
+		// Unknown models are left to the api
+		{model: "llama3.2", want: full("llama3.2"), warnings: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			req := full(tt.model)
+			warnings := checkCapabilities(&req)
+			if !reflect.DeepEqual(req, tt.want) {
+				t.Errorf("request = %+v, want %+v", req, tt.want)
+			}
+			if len(warnings) != len(tt.warnings) {
+				t.Fatalf("warnings = %q, want ones about %q", warnings, tt.warnings)
+			}
+			for i, feature := range tt.warnings {
+				if !strings.Contains(warnings[i], tt.model+" doesn't support "+feature) {
+					t.Errorf("warning %d = %q, want it about %s", i, warnings[i], feature)
+				}
+			}
+		})
+	}
+}
+
+func TestCompleteAdjustsForModel(t *testing.T) {
+	testEnv(t)
+	client := answering("```\ncat\n```")
+	c, err := complete(client, openai.ChatCompletionRequest{Model: "o1-mini", N: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := client.sent(); len(sent) != 1 || sent[0].N != 0 {
+		t.Errorf("sent %+v, want it without multiple choices", sent)
+	}
+	if len(c.Warnings) != 1 {
+		t.Errorf("warnings = %q, want one about multiple choices", c.Warnings)
+	}
+}
+
+func TestFoldSystemMessages(t *testing.T) {
+	system := func(content string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: content}
+	}
+	user := func(content string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: content}
+	}
+	assistant := func(content string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content}
+	}
+	tests := []struct {
+		name     string
+		messages []openai.ChatCompletionMessage
+		want     []openai.ChatCompletionMessage
+		folded   bool
+	}{
+		{name: "no system messages", messages: []openai.ChatCompletionMessage{user("a cat")}, want: []openai.ChatCompletionMessage{user("a cat")}},
+		{name: "style hint", messages: []openai.ChatCompletionMessage{system("Use blocks."), user("a cat")}, want: []openai.ChatCompletionMessage{user("Use blocks.\n\na cat")}, folded: true},
+		{
+			name:     "into the latest prompt",
+			messages: []openai.ChatCompletionMessage{system("Use blocks."), system("Only cats."), user("a cat"), assistant("=^.^="), user("a bigger one")},
+			want:     []openai.ChatCompletionMessage{user("a cat"), assistant("=^.^="), user("Use blocks.\n\nOnly cats.\n\na bigger one")},
+			folded:   true,
+		},
+		{name: "no prompt", messages: []openai.ChatCompletionMessage{system("Use blocks.")}, want: []openai.ChatCompletionMessage{user("Use blocks.")}, folded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, folded := foldSystemMessages(tt.messages)
+			if folded != tt.folded || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("foldSystemMessages() = %+v, %v, want %+v, %v", got, folded, tt.want, tt.folded)
+			}
+		})
+	}
+}
+
+func TestCompleteFoldsStyleForModel(t *testing.T) {
+	testEnv(t)
+	history := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "a cat"}}
+	for _, tt := range []struct {
+		model  string
+		folded bool
+	}{
+		{model: "o1-mini", folded: true},
+		{model: "o1-preview", folded: true},
+		{model: "gpt-4o", folded: false},
+	} {
+		t.Run(tt.model, func(t *testing.T) {
+			client := answering("```\ncat\n```")
+			c, err := complete(client, openai.ChatCompletionRequest{Model: tt.model, Messages: withStyle(history, "blocks")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages := client.sent()[0].Messages
+			hint := styleHints["blocks"]
+			if tt.folded {
+				if len(messages) != 1 || messages[0].Role != openai.ChatMessageRoleUser || messages[0].Content != hint+"\n\na cat" {
+					t.Errorf("sent %+v, want the style hint in the prompt", messages)
+				}
+				if len(c.Warnings) != 1 || !strings.Contains(c.Warnings[0], "doesn't support system messages") {
+					t.Errorf("warnings = %q, want one about system messages", c.Warnings)
+				}
+			} else if len(messages) != 2 || messages[0].Role != openai.ChatMessageRoleSystem || len(c.Warnings) != 0 {
+				t.Errorf("sent %+v with warnings %q, want the style hint as a system message", messages, c.Warnings)
+			}
+			if history[0].Content != "a cat" {
+				t.Errorf("history = %+v, want it left as it was", history)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// exampleArt is returned in place of a real response when no api key is set,
+// unless there are examples of the user's own to use.
+const exampleArt = "```\n    _____\\    _______\n   /      \\  |      /\\\n  /_______/  |_____/  \\\n |   \\   /        /   /\n  \\   \\ MISSING \\/   /\n   \\  /   API    \\__/_\n    \\/ ___KEY_ /\\\n      /  \\    /  \\\n     /\\   \\  /   /\n       \\   \\/   /\n        \\___\\__/\n```"
+
+// ChatClient is the part of the openai client used to generate art, so the
+// chat and one-shot generation don't depend on how completions are made.
+type ChatClient interface {
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
+
+// NewChatClient returns a client for the openai api keys in the environment,
+// or one that answers with example art when there's no key.
+func NewChatClient() ChatClient {
+	keys := apiKeys()
+	if len(keys) == 0 {
+		return exampleClient{}
+	}
+	httpClient := newHTTPClient()
+	newClient := func(key string) StreamingClient {
+		config := openai.DefaultConfig(key)
+		config.HTTPClient = httpClient
+		if url := os.Getenv("OPENAI_BASE_URL"); url != "" {
+			// Any api compatible with openai's, e.g. a local Ollama
+			config.BaseURL = url
+		}
+		return openai.NewClientWithConfig(config)
+	}
+	var client StreamingClient
+	if len(keys) == 1 {
+		client = newClient(keys[0])
+	} else {
+		client = newRotatingClient(keys, newClient)
+	}
+	if bucket := limiter(); bucket != nil {
+		return rateLimitedClient{client: client, bucket: bucket}
+	}
+	return client
+}
+
+// newHTTPClient returns the client requests to openai are made with. It goes
+// through HTTP_PROXY/HTTPS_PROXY when set, and trusts the certificates in the
+// ASCII_CA_BUNDLE file on top of the system ones.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if bundle := os.Getenv("ASCII_CA_BUNDLE"); bundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(bundle)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not read ASCII_CA_BUNDLE: %v\n", err)
+		} else if !pool.AppendCertsFromPEM(pem) {
+			fmt.Fprintf(os.Stderr, "No certificates found in ASCII_CA_BUNDLE: %s\n", bundle)
+		} else {
+			transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// Generation is the outcome of a one-shot prompt, shaped for JSON output.
+type Generation struct {
+	Prompt       string `json:"prompt"`
+	Model        string `json:"model"`
+	Art          string `json:"art"`
+	Tokens       int    `json:"tokens"`
+	FinishReason string `json:"finish_reason"`
+	Error        string `json:"error"`
+}
+
+// completion is a response in the shape the chat works with, whichever
+// provider it came from.
+type completion struct {
+	Text   string
+	Model  string
+	Tokens int
+	// Tokens split by what was sent and what was generated, for pricing
+	PromptTokens     int
+	CompletionTokens int
+	FinishReason     string
+	// Warnings about the request, like features the model doesn't support
+	Warnings []string
+}
+
+// The finish reasons the chat acts on.
+const (
+	finishStop   = "stop"
+	finishLength = "length"
+)
+
+// fromOpenAI normalizes an openai response, using its first choice.
+func fromOpenAI(resp openai.ChatCompletionResponse) completion {
+	c := completion{
+		Model:            resp.Model,
+		Tokens:           resp.Usage.TotalTokens,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}
+	if len(resp.Choices) > 0 {
+		c.Text = resp.Choices[0].Message.Content
+		c.FinishReason = string(resp.Choices[0].FinishReason)
+	}
+	return c
+}
+
+// providerModels are the models each provider is chatted with by default,
+// since one provider's model names mean nothing to another.
+var providerModels = map[string][]string{
+	"openai":    {"gpt-4o-mini", "gpt-4o"},
+	"anthropic": {"claude-3-5-haiku-latest", "claude-3-5-sonnet-latest"},
+	"ollama":    {"llama3.2"},
+}
+
+// models returns the models the chat can cycle through, set by a comma
+// separated ASCII_MODELS. The first one is used to start with. Without it,
+// they're the defaults for the provider, which ASCII_<PROVIDER>_MODELS (e.g.
+// ASCII_OLLAMA_MODELS) replaces. Providers without defaults get openai's.
+func models() []string {
+	defaults, ok := providerModels[provider()]
+	if !ok {
+		defaults = providerModels["openai"]
+	}
+	defaults = envList("ASCII_"+strings.ToUpper(provider())+"_MODELS", defaults)
+	return envList("ASCII_MODELS", defaults)
+}
+
+// newRequest builds a request for the conversation so far, steered towards
+// art in the given style.
+func newRequest(model string, history []openai.ChatCompletionMessage, seed *int, style string) openai.ChatCompletionRequest {
+	req := openai.ChatCompletionRequest{
+		Model:     model,
+		MaxTokens: maxTokens(),
+		Messages:  withStyle(history, style),
+		Seed:      seed,
+		Stop:      stopSequences(),
+	}
+	if t := temperature(); t != nil {
+		req.Temperature = float32(*t)
+	}
+	if p := topP(); p != nil {
+		req.TopP = float32(*p)
+	}
+	if jsonMode() {
+		req = withJSONMode(req)
+	}
+	return req
+}
+
+// temperature and topP return the sampling settings set by ASCII_TEMPERATURE
+// and ASCII_TOP_P, nil to leave them to the model.
+func temperature() *float64 {
+	return envOptionalFloat("ASCII_TEMPERATURE")
+}
+
+func topP() *float64 {
+	return envOptionalFloat("ASCII_TOP_P")
+}
+
+// stopSequences returns where generation should halt, set by a comma separated
+// ASCII_STOP with \n for newlines, e.g. "\n```\n\n" to stop at a fence closed
+// by a blank line, before any prose after the art. None by default.
+func stopSequences() []string {
+	stops := []string{}
+	for _, stop := range strings.Split(os.Getenv("ASCII_STOP"), ",") {
+		if stop = strings.ReplaceAll(stop, `\n`, "\n"); strings.TrimSpace(stop) != "" {
+			stops = append(stops, stop)
+		}
+	}
+	if len(stops) == 0 {
+		return nil
+	}
+	return stops
+}
+
+// closeFence adds back a closing fence that a stop sequence cut off, so the
+// art can still be found.
+func closeFence(text string) string {
+	if strings.Count(text, fence)%2 == 1 {
+		return strings.TrimRight(text, "\n") + "\n" + fence
+	}
+	return text
+}
+
+// complete sends a request with client. Requests that clearly never reached
+// openai are retried, see retries.
+func complete(client ChatClient, req openai.ChatCompletionRequest) (completion, error) {
+	return completeTo(client, req, nil)
+}
+
+// completeTo is complete, passing streamed text to onChunk as it arrives.
+func completeTo(client ChatClient, req openai.ChatCompletionRequest, onChunk func(string)) (completion, error) {
+	warnings := checkCapabilities(&req)
+	start := time.Now()
+	if streamer, ok := client.(StreamingClient); ok && envBool("ASCII_STREAM", false) {
+		c, err := completeStream(streamer, req, onChunk)
+		if len(req.Stop) > 0 && c.FinishReason == finishStop {
+			c.Text = closeFence(c.Text)
+		}
+		if jsonMode() {
+			c.Text = fromJSONResponse(c.Text)
+		}
+		c.Warnings = warnings
+		logRequest(req, c, err, time.Since(start))
+		return c, err
+	}
+	resp, err := client.CreateChatCompletion(context.Background(), req)
+	for attempt := 1; err != nil && retryable(err) && attempt <= retries(); attempt++ {
+		time.Sleep(time.Duration(attempt) * time.Second)
+		resp, err = client.CreateChatCompletion(context.Background(), req)
+	}
+	if err == nil && len(resp.Choices) == 0 {
+		err = errors.New("no choices returned")
+	}
+	if err != nil {
+		logRequest(req, completion{}, err, time.Since(start))
+		return completion{}, err
+	}
+	c := fromOpenAI(resp)
+	if len(req.Stop) > 0 && c.FinishReason == finishStop {
+		c.Text = closeFence(c.Text)
+	}
+	if jsonMode() {
+		// Models that can't be held to JSON have still been asked for it
+		c.Text = fromJSONResponse(c.Text)
+	}
+	c.Warnings = warnings
+	logRequest(req, c, nil, time.Since(start))
+	return c, nil
+}
+
+// exampleClient stands in for openai when there's no api key, answering every
+// request with example art.
+type exampleClient struct{}
+
+func (exampleClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	return openai.ChatCompletionResponse{
+		Model: req.Model,
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: exampleResponse()},
+			FinishReason: openai.FinishReasonStop,
+		}},
+	}, nil
+}
+
+// Generate sends a single prompt outside of the chat and returns the art from
+// the response with its fences stripped.
+func Generate(client ChatClient, prompt string) (Generation, error) {
+	return generate(client, prompt, newRequest(models()[0], []openai.ChatCompletionMessage{{
+		Role:    openai.ChatMessageRoleUser,
+		Content: prompt,
+	}}, envSeed(), envStyle()))
+}
+
+// generate sends req, made for prompt, and returns the art from the response.
+func generate(client ChatClient, prompt string, req openai.ChatCompletionRequest) (Generation, error) {
+	gen := Generation{Prompt: prompt, Model: req.Model}
+	resp, err := complete(client, req)
+	if err != nil {
+		gen.Error = err.Error()
+		return gen, err
+	}
+	if resp.Model != "" {
+		gen.Model = resp.Model
+	}
+	gen.Tokens = resp.Tokens
+	gen.FinishReason = resp.FinishReason
+	text := resp.Text
+	if art, ok := imageArt(text); ok {
+		text = art
+	}
+	art, ok := extractArt(text)
+	if !ok {
+		err = errors.New("no ascii art found in the response")
+		gen.Error = err.Error()
+		return gen, err
+	}
+	gen.Art = stripFence(art)
+	return gen, nil
+}
@@ -0,0 +1,287 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestNewChatClientWithoutKey(t *testing.T) {
+	testEnv(t)
+	if _, ok := NewChatClient().(exampleClient); !ok {
+		t.Errorf("NewChatClient() without a key isn't the example client")
+	}
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	if _, ok := NewChatClient().(exampleClient); ok {
+		t.Errorf("NewChatClient() with a key is the example client")
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	history := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "a cat"}}
+	cat := "```\n=^.^=\n```"
+	tests := []struct {
+		name    string
+		client  ChatClient
+		want    string
+		wantErr string
+	}{
+		{name: "no api key", client: exampleClient{}, want: exampleArt},
+		{name: "success", client: answering(cat), want: cat},
+		{name: "error", client: failing(errors.New("bad request")), wantErr: "bad request"},
+		{name: "no choices", client: &fakeClient{}, wantErr: "no choices returned"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestChat(t, tt.client)
+			got, err := m.SendMessage(history)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("SendMessage() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SendMessage() error = %v", err)
+			}
+			if got.Text != tt.want {
+				t.Errorf("SendMessage() text = %q, want %q", got.Text, tt.want)
+			}
+			if got.FinishReason != finishStop {
+				t.Errorf("SendMessage() finish reason = %q, want %q", got.FinishReason, finishStop)
+			}
+		})
+	}
+}
+
+func TestSendMessageRequest(t *testing.T) {
+	client := answering("ok")
+	m := newTestChat(t, client)
+	history := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "a cat"}}
+	if _, err := m.SendMessage(history); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	sent := client.sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(sent))
+	}
+	if sent[0].Model != m.model {
+		t.Errorf("request model = %q, want %q", sent[0].Model, m.model)
+	}
+	last := sent[0].Messages[len(sent[0].Messages)-1]
+	if last.Role != openai.ChatMessageRoleUser || last.Content != "a cat" {
+		t.Errorf("last message sent = %+v, want the prompt", last)
+	}
+}
+
+func TestSendMessageOffline(t *testing.T) {
+	client := answering("ok")
+	m := newTestChat(t, client)
+	m.offline = true
+	got, err := m.SendMessage(nil)
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if got.Text != exampleArt {
+		t.Errorf("SendMessage() offline text = %q, want the example art", got.Text)
+	}
+	if len(client.sent()) != 0 {
+		t.Errorf("SendMessage() offline sent %d requests", len(client.sent()))
+	}
+}
+
+func TestFromOpenAI(t *testing.T) {
+	tests := []struct {
+		name string
+		resp openai.ChatCompletionResponse
+		want completion
+	}{
+		{
+			name: "openai",
+			resp: openai.ChatCompletionResponse{
+				Model: "gpt-4o-mini",
+				Usage: openai.Usage{PromptTokens: 10, CompletionTokens: 20, TotalTokens: 30},
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{Content: "first"}, FinishReason: openai.FinishReasonStop},
+					{Message: openai.ChatCompletionMessage{Content: "second"}, FinishReason: openai.FinishReasonStop},
+				},
+			},
+			want: completion{Text: "first", Model: "gpt-4o-mini", Tokens: 30, PromptTokens: 10, CompletionTokens: 20, FinishReason: finishStop},
+		},
+		{
+			// Compatible apis like Ollama's don't always report usage
+			name: "without usage",
+			resp: openai.ChatCompletionResponse{
+				Model:   "llama3.2",
+				Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "cut"}, FinishReason: openai.FinishReasonLength}},
+			},
+			want: completion{Text: "cut", Model: "llama3.2", FinishReason: finishLength},
+		},
+		{
+			name: "no choices",
+			resp: openai.ChatCompletionResponse{Model: "gpt-4o", Usage: openai.Usage{TotalTokens: 5}},
+			want: completion{Model: "gpt-4o", Tokens: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fromOpenAI(tt.resp); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fromOpenAI() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteStream(t *testing.T) {
+	testEnv(t)
+	usage := openai.ChatCompletionStreamResponse{
+		Model: "gpt-4o-mini",
+		Usage: &openai.Usage{PromptTokens: 4, CompletionTokens: 6, TotalTokens: 10},
+	}
+	client := streaming(t, chunk("```\n/\\_", ""), chunk("/\\\n```", openai.FinishReasonStop), usage)
+	got, err := completeStream(client, openai.ChatCompletionRequest{Model: "gpt-4o"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := completion{Text: "```\n/\\_/\\\n```", Model: "gpt-4o-mini", Tokens: 10, PromptTokens: 4, CompletionTokens: 6, FinishReason: finishStop}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("completeStream() = %+v, want %+v", got, want)
+	}
+}
+
+func TestStopSequences(t *testing.T) {
+	tests := []struct {
+		stop string
+		want []string
+	}{
+		{stop: "", want: nil},
+		{stop: " , ", want: nil},
+		{stop: `\n` + "```" + `\n\n`, want: []string{"\n```\n\n"}},
+		// Sequences of only whitespace would stop at any blank line
+		{stop: `THE END,\n\n\n`, want: []string{"THE END"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("ASCII_STOP", tt.stop)
+		if got := stopSequences(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("stopSequences() = %q with ASCII_STOP=%q, want %q", got, tt.stop, tt.want)
+		}
+	}
+}
+
+func TestStopSequenceSent(t *testing.T) {
+	testEnv(t)
+	t.Setenv("ASCII_STOP", `\n`+"```"+`\n\n`)
+	// The stop sequence itself isn't sent back, so neither is the closing fence
+	client := answering("Here:\n```\n(o.o)")
+	gen, err := Generate(client, "a face")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := client.sent(); len(sent) != 1 || !reflect.DeepEqual(sent[0].Stop, []string{"\n```\n\n"}) {
+		t.Errorf("sent %+v, want the stop sequence set", sent)
+	}
+	if gen.Art != "(o.o)" {
+		t.Errorf("art = %q, want it found with its fence closed again", gen.Art)
+	}
+}
+
+func TestStopSequenceUnset(t *testing.T) {
+	testEnv(t)
+	t.Setenv("ASCII_STOP", "")
+	client := answering("```\n(o.o)\n```")
+	if _, err := Generate(client, "a face"); err != nil {
+		t.Fatal(err)
+	}
+	if sent := client.sent(); len(sent) != 1 || sent[0].Stop != nil {
+		t.Errorf("sent %+v, want no stop sequences", sent)
+	}
+}
+
+func TestCloseFence(t *testing.T) {
+	for text, want := range map[string]string{
+		"```\n(o.o)\n":    "```\n(o.o)\n```",
+		"```\n(o.o)\n```": "```\n(o.o)\n```",
+		"no art here":     "no art here",
+	} {
+		if got := closeFence(text); got != want {
+			t.Errorf("closeFence(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestModels(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{name: "openai by default", want: []string{"gpt-4o-mini", "gpt-4o"}},
+		{name: "anthropic", env: map[string]string{"ASCII_PROVIDER": "Anthropic"}, want: []string{"claude-3-5-haiku-latest", "claude-3-5-sonnet-latest"}},
+		{name: "ollama from its url", env: map[string]string{"OPENAI_BASE_URL": "http://localhost:11434/v1"}, want: []string{"llama3.2"}},
+		{name: "unknown provider gets openai's", env: map[string]string{"ASCII_PROVIDER": "groq"}, want: []string{"gpt-4o-mini", "gpt-4o"}},
+		{name: "provider's own list", env: map[string]string{"ASCII_PROVIDER": "ollama", "ASCII_OLLAMA_MODELS": "qwen2.5, mistral"}, want: []string{"qwen2.5", "mistral"}},
+		{name: "another provider's list ignored", env: map[string]string{"ASCII_PROVIDER": "anthropic", "ASCII_OLLAMA_MODELS": "qwen2.5"}, want: []string{"claude-3-5-haiku-latest", "claude-3-5-sonnet-latest"}},
+		{name: "ASCII_MODELS wins", env: map[string]string{"ASCII_PROVIDER": "ollama", "ASCII_OLLAMA_MODELS": "qwen2.5", "ASCII_MODELS": "my-model"}, want: []string{"my-model"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testEnv(t)
+			for _, key := range []string{"ASCII_OPENAI_MODELS", "ASCII_ANTHROPIC_MODELS", "ASCII_OLLAMA_MODELS", "ASCII_GROQ_MODELS"} {
+				t.Setenv(key, "")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if got := models(); !slices.Equal(got, tt.want) {
+				t.Errorf("models() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateProviderModel(t *testing.T) {
+	testEnv(t)
+	t.Setenv("ASCII_PROVIDER", "ollama")
+	t.Setenv("ASCII_OLLAMA_MODELS", "")
+	client := answering("```\n(o.o)\n```")
+	if _, err := Generate(client, "a face"); err != nil {
+		t.Fatal(err)
+	}
+	if sent := client.sent(); len(sent) != 1 || sent[0].Model != "llama3.2" {
+		t.Errorf("sent %+v, want ollama's default model", sent)
+	}
+}
+
+func TestProviderSwitchModel(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  string
+		config string
+		want   string
+	}{
+		{name: "switches to the provider's default", model: "gpt-4o-mini", config: "ASCII_PROVIDER=ollama\n", want: "llama3.2"},
+		{name: "explicit models kept to", model: "gpt-4o-mini", config: "ASCII_PROVIDER=anthropic\nASCII_MODELS=my-model\n", want: "my-model"},
+		{name: "model that still fits kept", model: "gpt-4o", config: "ASCII_STYLE=blocks\n", want: "gpt-4o"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestChat(t, exampleClient{})
+			t.Setenv("ASCII_OLLAMA_MODELS", "")
+			t.Setenv("ASCII_ANTHROPIC_MODELS", "")
+			t.Setenv("ASCII_STYLE", "")
+			m.model = tt.model
+			editConfigTo(t, t.TempDir(), tt.config)
+			withShellEnv(t)
+			m.configEdited(configEditedMsg{before: map[string]string{}})
+			if m.model != tt.want {
+				t.Errorf("model = %q after the edit, want %q", m.model, tt.want)
+			}
+		})
+	}
+}
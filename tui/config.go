@@ -0,0 +1,204 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joho/godotenv"
+)
+
+// configFile is where the settings are kept, loaded into the environment at
+// startup.
+const configFile = ".env"
+
+// shellEnv holds the variables that were already set when the program
+// started. They win over the config, when it's loaded and when it's reloaded
+// after an edit.
+var shellEnv map[string]bool
+
+// LoadConfig loads the settings in the config into the environment, leaving
+// variables that are already set alone.
+func LoadConfig() error {
+	shellEnv = map[string]bool{}
+	for _, v := range os.Environ() {
+		key, _, _ := strings.Cut(v, "=")
+		shellEnv[key] = true
+	}
+	return godotenv.Load(configFile)
+}
+
+// settingKinds are the settings that have to be a number or a boolean to be
+// used, for checking an edited config before it's applied.
+var settingKinds = map[string]string{
+	"ASCII_AUTO_COPY":           "bool",
+	"ASCII_CAPTIONS":            "bool",
+	"ASCII_CENTER_PROSE":        "bool",
+	"ASCII_DEBUG":               "bool",
+	"ASCII_IMAGE_INVERT":        "bool",
+	"ASCII_JSON_MODE":           "bool",
+	"ASCII_LOG_BODIES":          "bool",
+	"ASCII_MINIMAL":             "bool",
+	"ASCII_ROLE_COMPOSER":       "bool",
+	"ASCII_STICKY_SCROLL":       "bool",
+	"ASCII_STREAM":              "bool",
+	"ASCII_TRIM_BLANK_LINES":    "bool",
+	"ASCII_TRIM_TRAILING":       "bool",
+	"RETRY_ENABLED":             "bool",
+	"ASCII_AUTOSAVE_SECONDS":    "int",
+	"ASCII_EXAMPLES_COUNT":      "int",
+	"ASCII_FIRST_TOKEN_SECONDS": "int",
+	"ASCII_FRAME_MS":            "int",
+	"ASCII_IDLE_MINUTES":        "int",
+	"ASCII_PADDING":             "int",
+	"ASCII_PROSE_WIDTH":         "int",
+	"ASCII_RATE_BURST":          "int",
+	"ASCII_RATE_LIMIT":          "int",
+	"ASCII_RECONNECT_SECONDS":   "int",
+	"ASCII_RESIZE_DEBOUNCE_MS":  "int",
+	"ASCII_STREAM_CPS":          "int",
+	"ASCII_THINKING_MS":         "int",
+	"ASCII_TIMEOUT_SECONDS":     "int",
+	"ASCII_TYPEWRITER_MS":       "int",
+	"OPENAI_MAX_TOKENS":         "int",
+	"OPENAI_SEED":               "int",
+	"RETRY_MAX":                 "int",
+	"ASCII_IMAGE_ASPECT":        "float",
+	"ASCII_TEMPERATURE":         "float",
+	"ASCII_TOP_P":               "float",
+}
+
+// kindNames describe the kinds of setting in errors.
+var kindNames = map[string]string{
+	"bool":  "true or false",
+	"int":   "a whole number",
+	"float": "a number",
+}
+
+// checkConfig returns what's wrong with the settings in vars, sorted by name.
+func checkConfig(vars map[string]string) []string {
+	problems := []string{}
+	for key, value := range vars {
+		var err error
+		switch settingKinds[key] {
+		case "bool":
+			_, err = strconv.ParseBool(value)
+		case "int":
+			_, err = strconv.Atoi(value)
+		case "float":
+			_, err = strconv.ParseFloat(value, 64)
+		}
+		if err != nil && value != "" {
+			problems = append(problems, fmt.Sprintf("%s=%q should be %s", key, value, kindNames[settingKinds[key]]))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// editor returns the command to edit the config with, $VISUAL or $EDITOR,
+// falling back to whichever of nano and vi is installed.
+func editor() ([]string, error) {
+	for _, key := range []string{"VISUAL", "EDITOR"} {
+		if command := strings.Fields(os.Getenv(key)); len(command) > 0 {
+			return command, nil
+		}
+	}
+	for _, name := range []string{"nano", "vi"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return []string{path}, nil
+		}
+	}
+	return nil, errors.New("set $EDITOR to the editor to use")
+}
+
+// configEditedMsg is sent when the editor the config was opened in exits.
+// before is what the config held when it was opened, nil if it never was.
+type configEditedMsg struct {
+	before map[string]string
+	err    error
+}
+
+// editConfig suspends the chat and opens the config in the editor.
+func editConfig() tea.Cmd {
+	before, err := godotenv.Read(configFile)
+	if err != nil {
+		return func() tea.Msg { return configEditedMsg{err: err} }
+	}
+	command, err := editor()
+	if err != nil {
+		return func() tea.Msg { return configEditedMsg{err: err} }
+	}
+	return tea.ExecProcess(exec.Command(command[0], append(command[1:], configFile)...), func(err error) tea.Msg {
+		return configEditedMsg{before: before, err: err}
+	})
+}
+
+// reloadConfig applies the edited config to the environment, unless any of it
+// is invalid, in which case the settings are left as they were. Settings taken
+// out of the config are unset. Variables set in the shell are left alone, the
+// same as at startup.
+func reloadConfig(before map[string]string) error {
+	after, err := godotenv.Read(configFile)
+	if err != nil {
+		return err
+	}
+	if problems := checkConfig(after); len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok && !shellEnv[key] {
+			os.Unsetenv(key)
+		}
+	}
+	for key, value := range after {
+		if !shellEnv[key] {
+			os.Setenv(key, value)
+		}
+	}
+	return nil
+}
+
+// configEdited reloads the config once it's been edited and reports how it
+// went in the transcript.
+func (m *chatModel) configEdited(msg configEditedMsg) {
+	err := msg.err
+	if err == nil {
+		err = reloadConfig(msg.before)
+	}
+	if err != nil && msg.before == nil {
+		m.messages = append(m.messages, statusStyle.Render("Couldn't open "+configFile+": "+err.Error()))
+	} else if err != nil {
+		m.messages = append(m.messages, statusStyle.Render("Kept the previous settings, the config couldn't be used: "+err.Error()))
+	} else {
+		// The client is made from the api keys, url and rate limit, which may
+		// have changed
+		resetLimiter()
+		m.aiClient = NewChatClient()
+		m.exampleMode = len(apiKeys()) == 0
+		if !slices.Contains(models(), m.model) {
+			// Switched to a provider the model isn't one of
+			m.model = models()[0]
+		}
+		if file := os.Getenv("ASCII_HISTORY_FILE"); file != m.prompts.file {
+			m.prompts = newPromptHistory(file)
+		}
+		if padding := max(0, envInt("ASCII_PADDING", 1)); padding != m.padding {
+			m.padding = padding
+			if m.width > 0 {
+				m.resize(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+			}
+		}
+		m.messages = append(m.messages, statusStyle.Render("Reloaded the settings in "+configFile+"."))
+	}
+	m.refresh()
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// editConfigTo runs a test in dir with the config holding config.
+func editConfigTo(t *testing.T, dir string, config string) {
+	t.Helper()
+	chdir(t, dir)
+	if err := os.WriteFile(configFile, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// withShellEnv has the test run as if keys were set in the shell.
+func withShellEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	saved := shellEnv
+	shellEnv = map[string]bool{}
+	for _, key := range keys {
+		shellEnv[key] = true
+	}
+	t.Cleanup(func() { shellEnv = saved })
+}
+
+func TestReloadConfig(t *testing.T) {
+	editConfigTo(t, testEnv(t), "ASCII_STYLE=braille\nASCII_NEW=2\n")
+	withShellEnv(t, "ASCII_STYLE", "ASCII_SHELL")
+	t.Setenv("ASCII_STYLE", "blocks")
+	t.Setenv("ASCII_SHELL", "1")
+	t.Setenv("ASCII_GONE", "1")
+	t.Setenv("ASCII_NEW", "")
+	before := map[string]string{"ASCII_STYLE": "ascii", "ASCII_SHELL": "0", "ASCII_GONE": "1"}
+	if err := reloadConfig(before); err != nil {
+		t.Fatalf("reloadConfig() error = %v", err)
+	}
+	for key, want := range map[string]string{"ASCII_STYLE": "blocks", "ASCII_SHELL": "1", "ASCII_NEW": "2"} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if _, ok := os.LookupEnv("ASCII_GONE"); ok {
+		t.Errorf("ASCII_GONE is still set after being taken out of the config")
+	}
+}
+
+func TestReloadInvalidConfig(t *testing.T) {
+	editConfigTo(t, testEnv(t), "ASCII_PADDING=wide\nASCII_STYLE=braille\n")
+	withShellEnv(t)
+	t.Setenv("ASCII_STYLE", "ascii")
+	err := reloadConfig(map[string]string{"ASCII_STYLE": "ascii"})
+	if err == nil || !strings.Contains(err.Error(), "ASCII_PADDING") {
+		t.Fatalf("reloadConfig() error = %v, want one about ASCII_PADDING", err)
+	}
+	if got := os.Getenv("ASCII_STYLE"); got != "ascii" {
+		t.Errorf("ASCII_STYLE = %q after an invalid config, want it left alone", got)
+	}
+}
+
+func TestConfigEdited(t *testing.T) {
+	m := newTestChat(t, exampleClient{})
+	dir := t.TempDir()
+	history := filepath.Join(dir, "history")
+	if err := os.WriteFile(history, []byte("an old prompt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	editConfigTo(t, dir, "OPENAI_API_KEY=sk-test\nASCII_RATE_LIMIT=30\nASCII_PADDING=3\nASCII_HISTORY_FILE="+history+"\n")
+	withShellEnv(t)
+	t.Setenv("ASCII_RATE_LIMIT", "")
+	t.Setenv("ASCII_PADDING", "")
+	if b := limiter(); b == nil || b.rate != 1 {
+		t.Fatalf("limiter() before the edit = %+v, want 60 a minute", b)
+	}
+	m.configEdited(configEditedMsg{before: map[string]string{}})
+	if m.exampleMode {
+		t.Errorf("still in example mode with an api key")
+	}
+	if b := limiter(); b == nil || b.rate != 0.5 {
+		t.Errorf("limiter() after the edit = %+v, want 30 a minute", b)
+	}
+	if limited, ok := m.aiClient.(rateLimitedClient); !ok || limited.bucket != limiter() {
+		t.Errorf("client isn't held back by the new limiter")
+	}
+	if m.padding != 3 || m.viewport.Width != 80-2*3 {
+		t.Errorf("padding = %d, viewport width = %d, want 3 and %d", m.padding, m.viewport.Width, 80-2*3)
+	}
+	if m.prompts.file != history || len(m.prompts.prompts) != 1 {
+		t.Errorf("prompt history = %+v, want the one in %s", m.prompts, history)
+	}
+}
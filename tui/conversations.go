@@ -0,0 +1,123 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// conversation is a saved chat that can be picked up again.
+type conversation struct {
+	id      string
+	title   string
+	updated time.Time
+}
+
+// conversationDir is where every chat is kept, one transcript per
+// conversation, so they can be resumed later.
+func conversationDir() string {
+	if dir := os.Getenv("ASCII_CONVERSATIONS_DIR"); dir != "" {
+		return dir
+	}
+	return ".ascii-conversations"
+}
+
+// newConversationID names a conversation after when it started, numbered if
+// another one started in the same second.
+func newConversationID() string {
+	started := time.Now().Format("20060102-150405")
+	id := started
+	for n := 2; ; n++ {
+		if _, err := os.Stat(conversationFile(id)); os.IsNotExist(err) {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", started, n)
+	}
+}
+
+func conversationFile(id string) string {
+	return filepath.Join(conversationDir(), id+".md")
+}
+
+// saveConversation writes the history under id. Conversations with nothing
+// in them aren't worth keeping.
+func saveConversation(id string, history []openai.ChatCompletionMessage) error {
+	if id == "" || len(history) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(conversationDir(), 0o700); err != nil {
+		return err
+	}
+	return writeFile(conversationFile(id), []byte(transcriptMarkdown(history, nil)), 0o600)
+}
+
+func loadConversation(id string) ([]turn, error) {
+	md, err := os.ReadFile(conversationFile(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no saved conversation %q in %s", id, conversationDir())
+	} else if err != nil {
+		return nil, err
+	}
+	return parseTranscript(string(md))
+}
+
+// listConversations returns the saved conversations, the most recent first,
+// titled by their first prompt.
+func listConversations() ([]conversation, error) {
+	entries, err := os.ReadDir(conversationDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	conversations := []conversation{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), ".md")
+		turns, err := loadConversation(id)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		title := ""
+		for _, t := range turns {
+			if t.role == openai.ChatMessageRoleUser {
+				title = strings.ReplaceAll(t.text, "\n", " ")
+				break
+			}
+		}
+		conversations = append(conversations, conversation{id: id, title: title, updated: info.ModTime()})
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		return conversations[i].updated.After(conversations[j].updated)
+	})
+	return conversations, nil
+}
+
+// Resume continues the saved conversation with the given id in place of this
+// one, restoring its history and transcript. It keeps being saved under id.
+func (m chatModel) Resume(id string) (chatModel, error) {
+	turns, err := loadConversation(id)
+	if err != nil {
+		return m, err
+	}
+	m.conversation = id
+	m.history = []openai.ChatCompletionMessage{}
+	m.messages = []string{}
+	m.welcome = ""
+	m.restoreTurns(turns)
+	return m, nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// price is what a model costs in dollars per 1k tokens.
+type price struct {
+	prompt     float64
+	completion float64
+}
+
+// defaultPrices are openai's list prices, ASCII_PRICES overrides them.
+var defaultPrices = map[string]price{
+	"gpt-4o-mini":   {prompt: 0.00015, completion: 0.0006},
+	"gpt-4o":        {prompt: 0.0025, completion: 0.01},
+	"gpt-4-turbo":   {prompt: 0.01, completion: 0.03},
+	"gpt-4":         {prompt: 0.03, completion: 0.06},
+	"gpt-3.5-turbo": {prompt: 0.0005, completion: 0.0015},
+	"o1-mini":       {prompt: 0.003, completion: 0.012},
+	"o1-preview":    {prompt: 0.015, completion: 0.06},
+}
+
+// prices returns the price table, with any overrides from ASCII_PRICES in the
+// form "model=prompt:completion,..." applied on top of the defaults.
+func prices() map[string]price {
+	table := map[string]price{}
+	for model, p := range defaultPrices {
+		table[model] = p
+	}
+	for _, entry := range strings.Split(os.Getenv("ASCII_PRICES"), ",") {
+		model, rates, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		promptRate, completionRate, ok := strings.Cut(rates, ":")
+		if !ok {
+			continue
+		}
+		p, err := strconv.ParseFloat(promptRate, 64)
+		if err != nil {
+			continue
+		}
+		c, err := strconv.ParseFloat(completionRate, 64)
+		if err != nil {
+			continue
+		}
+		table[model] = price{prompt: p, completion: c}
+	}
+	return table
+}
+
+// cost estimates what a completion cost in dollars, and whether the model's
+// price is known.
+func cost(c completion) (float64, bool) {
+	table := prices()
+	p, ok := table[c.Model]
+	if !ok {
+		// Responses name the dated snapshot, e.g. gpt-4o-mini-2024-07-18, so
+		// fall back to the longest model name it starts with
+		longest := ""
+		for model, modelPrice := range table {
+			if strings.HasPrefix(c.Model, model+"-") && len(model) > len(longest) {
+				longest, p, ok = model, modelPrice, true
+			}
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+	return float64(c.PromptTokens)/1000*p.prompt + float64(c.CompletionTokens)/1000*p.completion, true
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices string
+		c      completion
+		want   float64
+		known  bool
+	}{
+		{name: "default price", c: completion{Model: "gpt-4o", PromptTokens: 1000, CompletionTokens: 500}, want: 0.0025 + 0.005, known: true},
+		{name: "dated snapshot", c: completion{Model: "gpt-4o-mini-2024-07-18", PromptTokens: 2000, CompletionTokens: 1000}, want: 0.0003 + 0.0006, known: true},
+		{name: "longest prefix", c: completion{Model: "gpt-4-turbo-2024-04-09", PromptTokens: 1000}, want: 0.01, known: true},
+		{name: "override", prices: "gpt-4o=1:2", c: completion{Model: "gpt-4o", PromptTokens: 500, CompletionTokens: 500}, want: 1.5, known: true},
+		{name: "new model", prices: " llama3.2=0:0 , bad, x=1", c: completion{Model: "llama3.2", PromptTokens: 500}, want: 0, known: true},
+		{name: "bad override kept default", prices: "gpt-4=a:b", c: completion{Model: "gpt-4", CompletionTokens: 1000}, want: 0.06, known: true},
+		{name: "unknown", c: completion{Model: "mystery", PromptTokens: 1000}, want: 0, known: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASCII_PRICES", tt.prices)
+			got, known := cost(tt.c)
+			if known != tt.known || math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("cost() = %v, %v, want %v, %v", got, known, tt.want, tt.known)
+			}
+		})
+	}
+}
+
+func TestChatSessionCost(t *testing.T) {
+	client := answering("hello")
+	client.resp.Model = "gpt-4o-mini-2024-07-18"
+	client.resp.Usage = openai.Usage{PromptTokens: 1000, CompletionTokens: 1000, TotalTokens: 2000}
+	m := newTestChat(t, client)
+	t.Setenv("ASCII_PRICES", "")
+	for _, prompt := range []string{"hi", "again"} {
+		m.textarea.SetValue(prompt)
+		next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		next, _ = next.Update(awaitMsg[responseMsg](t, cmd))
+		m = next.(chatModel)
+	}
+	if math.Abs(m.sessionCost-0.0015) > 1e-12 {
+		t.Errorf("session cost = %v, want 0.0015", m.sessionCost)
+	}
+	if !strings.Contains(m.status, "(session $0.0015)") {
+		t.Errorf("status = %q, want the request and session cost", m.status)
+	}
+	if reset := m.reset(); reset.sessionCost != m.sessionCost {
+		t.Errorf("session cost = %v after a reset, want it kept", reset.sessionCost)
+	}
+}
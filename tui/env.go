@@ -0,0 +1,77 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envInt reads an integer from the environment, returning fallback when the
+// variable is unset or not a valid integer.
+func envInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// envBool reads a boolean from the environment, returning fallback when the
+// variable is unset or not a valid boolean.
+func envBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+// envList reads a comma separated list from the environment, returning
+// fallback when the variable is unset or lists nothing.
+func envList(key string, fallback []string) []string {
+	list := []string{}
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return fallback
+	}
+	return list
+}
+
+// envOptionalFloat reads a number from the environment, nil when the variable
+// is unset or not a valid number.
+func envOptionalFloat(key string) *float64 {
+	f, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+// envFloat reads a number from the environment, returning fallback when the
+// variable is unset or not a valid number.
+func envFloat(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fallback
+	}
+	return f
+}
@@ -0,0 +1,75 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// examplesShown counts example responses, for taking turns through them.
+// Responses are made in commands, so it's counted atomically.
+var examplesShown atomic.Int64
+
+// exampleResponse is what the chat answers with when there's no api key. The
+// art comes from the files in ASCII_EXAMPLES_DIR when there are any, keeping
+// the first ASCII_EXAMPLES_COUNT of them in name order if set, picked at
+// random or in turn depending on ASCII_EXAMPLES_ORDER. The built in example
+// is used otherwise.
+func exampleResponse() string {
+	examples := loadExamples(os.Getenv("ASCII_EXAMPLES_DIR"))
+	if count := envInt("ASCII_EXAMPLES_COUNT", 0); count > 0 && count < len(examples) {
+		examples = examples[:count]
+	}
+	if len(examples) == 0 {
+		return exampleArt
+	}
+	shown := int(examplesShown.Add(1) - 1)
+	return examples[pickExample(len(examples), os.Getenv("ASCII_EXAMPLES_ORDER"), shown)]
+}
+
+// pickExample returns which of n examples to show next. "round-robin" goes
+// through them in order, anything else picks one at random.
+func pickExample(n int, order string, shown int) int {
+	if order == "round-robin" {
+		return shown % n
+	}
+	return rand.Intn(n)
+}
+
+// loadExamples reads each non-empty file in dir as a piece of art, sorted by
+// name and fenced so it's picked up like art from a real response.
+func loadExamples(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	examples := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		art := strings.Trim(string(b), "\n")
+		if strings.TrimSpace(art) == "" {
+			continue
+		}
+		if !strings.HasPrefix(art, fence) {
+			art = fence + "\n" + art + "\n" + fence
+		}
+		examples = append(examples, art)
+	}
+	return examples
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// writeExamples fills a temporary ASCII_EXAMPLES_DIR with one file per piece
+// of art, named so they sort in the order given.
+func writeExamples(t *testing.T, arts ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	for i, art := range arts {
+		name := filepath.Join(dir, string(rune('a'+i))+".txt")
+		if err := os.WriteFile(name, []byte(art+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("ASCII_EXAMPLES_DIR", dir)
+}
+
+func TestPickExample(t *testing.T) {
+	for shown, want := range []int{0, 1, 2, 0, 1} {
+		if got := pickExample(3, "round-robin", shown); got != want {
+			t.Errorf("round-robin pick after %d shown = %d, want %d", shown, got, want)
+		}
+	}
+	seen := map[int]bool{}
+	for range 200 {
+		i := pickExample(3, "random", 0)
+		if i < 0 || i >= 3 {
+			t.Fatalf("random pick = %d, want one of 3", i)
+		}
+		seen[i] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("random picks covered %d of 3 examples in 200 tries", len(seen))
+	}
+}
+
+func TestExampleResponseRoundRobin(t *testing.T) {
+	testEnv(t)
+	writeExamples(t, "one", "two", "three")
+	t.Setenv("ASCII_EXAMPLES_ORDER", "round-robin")
+	t.Setenv("ASCII_EXAMPLES_COUNT", "2")
+	examplesShown.Store(0)
+	for _, want := range []string{"one", "two", "one"} {
+		if got := exampleResponse(); got != fence+"\n"+want+"\n"+fence {
+			t.Errorf("exampleResponse() = %q, want %q fenced", got, want)
+		}
+	}
+}
+
+func TestExampleResponseEmptyDir(t *testing.T) {
+	testEnv(t)
+	writeExamples(t, "  ")
+	if got := exampleResponse(); got != exampleArt {
+		t.Errorf("exampleResponse() = %q, want the built in example", got)
+	}
+}
+
+func TestExampleResponseConcurrent(t *testing.T) {
+	testEnv(t)
+	writeExamples(t, "one", "two")
+	t.Setenv("ASCII_EXAMPLES_ORDER", "round-robin")
+	examplesShown.Store(0)
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			exampleResponse()
+		}()
+	}
+	wg.Wait()
+	if got := examplesShown.Load(); got != 20 {
+		t.Errorf("examples shown = %d after 20 responses, want 20", got)
+	}
+}
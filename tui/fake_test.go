@@ -0,0 +1,208 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	// The database driver, registered by the cmd package outside of tests
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sashabaranov/go-openai"
+)
+
+// fakeClient answers every request with the same response or error, and
+// keeps the requests it was sent. The first requests can be turned away with
+// errors of their own first, see failingFirst.
+type fakeClient struct {
+	mu       sync.Mutex
+	resp     openai.ChatCompletionResponse
+	err      error
+	first    []error
+	requests []openai.ChatCompletionRequest
+}
+
+// answering returns a fake client that replies with text.
+func answering(text string) *fakeClient {
+	return &fakeClient{resp: openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: text},
+			FinishReason: openai.FinishReasonStop,
+		}},
+	}}
+}
+
+// failing returns a fake client that turns every request away with err.
+func failing(err error) *fakeClient {
+	return &fakeClient{err: err}
+}
+
+// failingFirst has the client turn away one request with each of errs before
+// going back to its usual answer.
+func (c *fakeClient) failingFirst(errs ...error) *fakeClient {
+	c.first = errs
+	return c
+}
+
+func (c *fakeClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	if len(c.first) > 0 {
+		err := c.first[0]
+		c.first = c.first[1:]
+		return openai.ChatCompletionResponse{}, err
+	}
+	resp := c.resp
+	if resp.Model == "" {
+		resp.Model = req.Model
+	}
+	return resp, c.err
+}
+
+// sent returns the requests the client has been sent so far.
+func (c *fakeClient) sent() []openai.ChatCompletionRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]openai.ChatCompletionRequest{}, c.requests...)
+}
+
+// streaming returns a client for a server that streams back chunks to every
+// request, as openai does.
+func streaming(t testing.TB, chunks ...openai.ChatCompletionStreamResponse) StreamingClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			b, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: %s\n\n", b)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL + "/v1"
+	return openai.NewClientWithConfig(config)
+}
+
+// chunk is a piece of a streamed response carrying text.
+func chunk(text string, reason openai.FinishReason) openai.ChatCompletionStreamResponse {
+	return openai.ChatCompletionStreamResponse{
+		Choices: []openai.ChatCompletionStreamChoice{{
+			Delta:        openai.ChatCompletionStreamChoiceDelta{Content: text},
+			FinishReason: reason,
+		}},
+	}
+}
+
+// testEnv keeps a test from reading the user's settings or writing files
+// outside of a temporary directory.
+func testEnv(t testing.TB) string {
+	t.Helper()
+	dir := t.TempDir()
+	for key, value := range map[string]string{
+		"OPENAI_API_KEY":           "",
+		"OPENAI_API_KEYS":          "",
+		"OPENAI_BASE_URL":          "",
+		"ASCII_PROVIDER":           "",
+		"ASCII_MODELS":             "",
+		"ASCII_STREAM":             "",
+		"ASCII_STREAM_CPS":         "",
+		"ASCII_JSON_MODE":          "",
+		"ASCII_RATE_LIMIT":         "",
+		"ASCII_EXAMPLES_DIR":       "",
+		"ASCII_HISTORY_FILE":       "",
+		"ASCII_DEBUG_LOG":          "",
+		"ASCII_AUTOSAVE_SECONDS":   "0",
+		"ASCII_RESIZE_DEBOUNCE_MS": "0",
+		"ASCII_CONVERSATIONS_DIR":  filepath.Join(dir, "conversations"),
+		"ASCII_RECOVERY_FILE":      filepath.Join(dir, "recovery.md"),
+		"ASCII_TRANSCRIPT_DIR":     filepath.Join(dir, "transcripts"),
+	} {
+		t.Setenv(key, value)
+	}
+	// The rate limit is read once and shared, read it again for each test
+	resetLimiter()
+	t.Cleanup(resetLimiter)
+	return dir
+}
+
+// chdir runs the rest of the test in dir. Go 1.23 has no t.Chdir.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// testDB has art saved to a new database in a temporary directory, since the
+// database is found relative to the working directory.
+func testDB(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, dir)
+}
+
+// newTestChat returns a chat laid out at 80x40 that sends its requests with
+// client.
+func newTestChat(t *testing.T, client ChatClient) chatModel {
+	t.Helper()
+	testEnv(t)
+	m := NewChatModel().WithClient(client)
+	m.resize(tea.WindowSizeMsg{Width: 80, Height: 40})
+	return m
+}
+
+// awaitMsg runs cmd, along with every command batched into it, and returns
+// the first message of type T it produces.
+func awaitMsg[T tea.Msg](t *testing.T, cmd tea.Cmd) T {
+	t.Helper()
+	msgs := make(chan tea.Msg, 16)
+	var run func(tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for _, cmd := range batch {
+				go run(cmd)
+			}
+			return
+		}
+		msgs <- msg
+	}
+	go run(cmd)
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-msgs:
+			if msg, ok := msg.(T); ok {
+				return msg
+			}
+		case <-timeout:
+			var want T
+			t.Fatalf("no %T from the command", want)
+			return want
+		}
+	}
+}
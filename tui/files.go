@@ -0,0 +1,88 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// writes is held while a file is being written, so quitting can wait for a
+// write that's under way instead of cutting it off. Requests log from their
+// own goroutines, so one can be mid-write when the chat exits.
+var writes sync.Mutex
+
+// writeFile writes data to path through a temporary file that's renamed into
+// place, so an interrupted write never leaves a file half written.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	writes.Lock()
+	defer writes.Unlock()
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// flushWrites waits up to timeout for a write that's under way to finish.
+func flushWrites(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		writes.Lock()
+		writes.Unlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// finish is the cleanup once the program has exited. The conversation is
+// saved one last time, whichever screen it was quit from, unless nothing was
+// said in it, and any write still going is given a moment to finish.
+func finish(final tea.Model) {
+	var chat *chatModel
+	switch m := final.(type) {
+	case chatModel:
+		chat = &m
+	case questionModel:
+		chat = m.chat
+	case promptModel:
+		chat = m.chat
+	case sheetModel:
+		chat = m.chat
+	case formModel:
+		chat = m.chat
+	case resumeModel:
+		chat = m.chat
+	case compareModel:
+		chat = m.chat
+	}
+	if chat != nil && len(chat.history) > 0 {
+		if err := saveConversation(chat.conversation, chat.history); err != nil {
+			debugf("saving conversation %s: %v", chat.conversation, err)
+		}
+	}
+	if !flushWrites(time.Second) {
+		debugf("gave up waiting on a write to finish")
+	}
+}
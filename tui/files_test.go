@@ -0,0 +1,111 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chat.md")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFile(path, []byte("new"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Errorf("read %q, %v, want the file replaced", data, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("stat %v, %v, want mode 0600", info, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("dir has %v, %v, want no temporary files left behind", entries, err)
+	}
+}
+
+func TestFlushWrites(t *testing.T) {
+	if !flushWrites(time.Second) {
+		t.Error("flushWrites() = false with nothing being written")
+	}
+	writes.Lock()
+	if flushWrites(10 * time.Millisecond) {
+		t.Error("flushWrites() = true while a write was still going")
+	}
+	released := make(chan struct{}, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		released <- struct{}{}
+		writes.Unlock()
+	}()
+	if !flushWrites(time.Second) {
+		t.Fatal("flushWrites() = false, want it to wait for the write")
+	}
+	select {
+	case <-released:
+	default:
+		t.Error("flushWrites() returned before the write finished")
+	}
+}
+
+func TestFinishSavesOnQuit(t *testing.T) {
+	history := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "a cat"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "```\n=^.^=\n```"},
+	}
+	t.Run("pending save completes", func(t *testing.T) {
+		m := newTestChat(t, answering("unused"))
+		m.history = history
+		// A write is under way when the program exits, and the final save
+		// waits its turn instead of racing it
+		writes.Lock()
+		done := make(chan struct{})
+		go func() {
+			finish(m)
+			close(done)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		if _, err := os.Stat(conversationFile(m.conversation)); err == nil {
+			t.Error("saved while another write was still going")
+		}
+		writes.Unlock()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("finish didn't return")
+		}
+		data, err := os.ReadFile(conversationFile(m.conversation))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "a cat") || !strings.Contains(string(data), "=^.^=") {
+			t.Errorf("saved %q, want the whole conversation", data)
+		}
+	})
+	t.Run("from another screen", func(t *testing.T) {
+		m := newTestChat(t, answering("unused"))
+		m.history = history
+		finish(questionModel{chat: &m})
+		if data, err := os.ReadFile(conversationFile(m.conversation)); err != nil || !strings.Contains(string(data), "a cat") {
+			t.Errorf("read %q, %v, want the conversation saved from the question screen", data, err)
+		}
+	})
+	t.Run("nothing said", func(t *testing.T) {
+		m := newTestChat(t, answering("unused"))
+		finish(m)
+		if _, err := os.Stat(conversationFile(m.conversation)); !os.IsNotExist(err) {
+			t.Errorf("stat = %v, want no file for an empty conversation", err)
+		}
+	})
+}
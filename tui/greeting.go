@@ -0,0 +1,41 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	_ "embed"
+	"os"
+	"strings"
+)
+
+//go:embed greeting.txt
+var defaultGreeting string
+
+// greetingArt returns the banner shown at the top of the chat. ASCII_GREETING
+// names a file of art to show instead, and setting it to an empty value hides
+// it. A file that can't be read falls back to the built in one.
+func greetingArt() string {
+	path, ok := os.LookupEnv("ASCII_GREETING")
+	if !ok {
+		return strings.TrimRight(defaultGreeting, "\n")
+	}
+	if path == "" {
+		return ""
+	}
+	art, err := os.ReadFile(path)
+	if err != nil {
+		debugf("reading ASCII_GREETING: %v", err)
+		return strings.TrimRight(defaultGreeting, "\n")
+	}
+	return strings.TrimRight(strings.ReplaceAll(string(art), "\r\n", "\n"), "\n")
+}
+
+// withGreeting puts the greeting above the start of the transcript, so it
+// scrolls away as the conversation grows.
+func withGreeting(greeting string, transcript string) string {
+	if greeting == "" {
+		return transcript
+	}
+	return bannerStyle.Render(greeting) + "\n\n" + transcript
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"strings"
+)
+
+// maxPromptHistory is how many submitted prompts are remembered.
+const maxPromptHistory = 100
+
+// promptHistory recalls previously submitted prompts like a shell does.
+type promptHistory struct {
+	prompts []string
+	// index is the recalled prompt, or len(prompts) when not navigating
+	index int
+	// file persists prompts across sessions when set
+	file string
+}
+
+func newPromptHistory(file string) promptHistory {
+	h := promptHistory{prompts: []string{}, file: file}
+	if file != "" {
+		if data, err := os.ReadFile(file); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if line != "" {
+					h.prompts = append(h.prompts, line)
+				}
+			}
+		}
+		if len(h.prompts) > maxPromptHistory {
+			h.prompts = h.prompts[len(h.prompts)-maxPromptHistory:]
+		}
+	}
+	h.index = len(h.prompts)
+	return h
+}
+
+// add remembers a submitted prompt and stops navigating.
+func (h *promptHistory) add(prompt string) {
+	if len(h.prompts) == 0 || h.prompts[len(h.prompts)-1] != prompt {
+		h.prompts = append(h.prompts, prompt)
+		if len(h.prompts) > maxPromptHistory {
+			h.prompts = h.prompts[1:]
+		}
+		if h.file != "" {
+			if f, err := os.OpenFile(h.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600); err == nil {
+				f.WriteString(strings.ReplaceAll(prompt, "\n", " ") + "\n")
+				f.Close()
+			}
+		}
+	}
+	h.index = len(h.prompts)
+}
+
+// navigating reports whether a recalled prompt is being shown.
+func (h promptHistory) navigating() bool {
+	return h.index < len(h.prompts)
+}
+
+// prev steps back to an older prompt, stopping at the oldest.
+func (h *promptHistory) prev() string {
+	if h.index > 0 {
+		h.index--
+	}
+	if h.index < len(h.prompts) {
+		return h.prompts[h.index]
+	}
+	return ""
+}
+
+// next steps forward to a newer prompt, returning an empty prompt once past
+// the newest.
+func (h *promptHistory) next() string {
+	if h.index < len(h.prompts) {
+		h.index++
+	}
+	if h.index < len(h.prompts) {
+		return h.prompts[h.index]
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// idleMsg is sent once nothing has been pressed for the idle timeout.
+type idleMsg struct{}
+
+// idleTimeout is how long the app waits for input before quitting,
+// ASCII_IDLE_MINUTES, so a kiosk or demo isn't left running indefinitely. 0,
+// the default, never quits.
+func idleTimeout() time.Duration {
+	return time.Duration(max(0, envInt("ASCII_IDLE_MINUTES", 0))) * time.Minute
+}
+
+// idleTimer sends idleMsg with send once it's run out. Every key or mouse
+// event starts it over.
+type idleTimer struct {
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+func newIdleTimer(timeout time.Duration, send func(tea.Msg)) *idleTimer {
+	return &idleTimer{
+		timeout: timeout,
+		timer:   time.AfterFunc(timeout, func() { send(idleMsg{}) }),
+	}
+}
+
+// filter watches the messages going to the program, starting the timer over
+// on input and quitting when it runs out.
+func (t *idleTimer) filter(_ tea.Model, msg tea.Msg) tea.Msg {
+	switch msg.(type) {
+	case tea.KeyMsg, tea.MouseMsg:
+		t.timer.Reset(t.timeout)
+	case idleMsg:
+		debugf("quitting after %s without input", t.timeout)
+		return tea.QuitMsg{}
+	}
+	return msg
+}
+
+func (t *idleTimer) stop() {
+	t.timer.Stop()
+}
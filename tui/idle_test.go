@@ -0,0 +1,78 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestIdleTimeout(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"":     0,
+		"5":    5 * time.Minute,
+		"-1":   0,
+		"soon": 0,
+	} {
+		t.Setenv("ASCII_IDLE_MINUTES", value)
+		if got := idleTimeout(); got != want {
+			t.Errorf("idleTimeout() with %q = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestIdleTimerReset(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	fired := make(chan tea.Msg, 1)
+	started := time.Now()
+	idle := newIdleTimer(timeout, func(msg tea.Msg) { fired <- msg })
+	defer idle.stop()
+
+	// Input keeps putting the timeout off, other messages don't
+	key := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")}
+	mouse := tea.MouseMsg{Action: tea.MouseActionMotion}
+	var last time.Time
+	for i := range 10 {
+		var msg tea.Msg = key
+		if i%2 == 1 {
+			msg = mouse
+		}
+		if got := idle.filter(nil, msg); !reflect.DeepEqual(got, msg) {
+			t.Fatalf("filter(%v) = %v, want input passed through", msg, got)
+		}
+		last = time.Now()
+		select {
+		case <-fired:
+			t.Fatalf("timed out %s in, with input every 30ms", time.Since(started))
+		case <-time.After(30 * time.Millisecond):
+		}
+	}
+	idle.filter(nil, tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	select {
+	case msg := <-fired:
+		if waited := time.Since(last); waited < timeout {
+			t.Errorf("timed out %s after the last key, want at least %s", waited, timeout)
+		}
+		if got := idle.filter(nil, msg); got != (tea.QuitMsg{}) {
+			t.Errorf("filter(%v) = %v, want the program told to quit", msg, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("never timed out once the input stopped")
+	}
+}
+
+func TestIdleTimerStop(t *testing.T) {
+	fired := make(chan tea.Msg, 1)
+	idle := newIdleTimer(20*time.Millisecond, func(msg tea.Msg) { fired <- msg })
+	idle.stop()
+	select {
+	case <-fired:
+		t.Error("timed out after being stopped")
+	case <-time.After(60 * time.Millisecond):
+	}
+}
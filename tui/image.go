@@ -0,0 +1,117 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// imageRamp maps pixels from dark to light, unless ASCII_IMAGE_RAMP is set.
+const imageRamp = "@%#*+=-:. "
+
+// imageWidth is how many columns wide converted images are.
+const imageWidth = 80
+
+// cellAspect is how wide a terminal cell is for its height, unless
+// ASCII_IMAGE_ASPECT says otherwise. Cells are about twice as tall as they're
+// wide, so images take half as many rows as columns to keep their shape.
+const cellAspect = 0.5
+
+// imageRows returns how many rows an image dx by dy pixels takes up at width
+// columns, scaled by the cell aspect so it isn't stretched.
+func imageRows(dx int, dy int, width int) int {
+	aspect := envFloat("ASCII_IMAGE_ASPECT", cellAspect)
+	if aspect <= 0 {
+		aspect = cellAspect
+	}
+	return max(1, int(math.Round(float64(dy)*float64(width)/float64(dx)*aspect)))
+}
+
+// imageArt looks for a base64 encoded image in a response, such as a data
+// URI, and converts it to fenced ascii art.
+func imageArt(content string) (string, bool) {
+	data, ok := findImageData(content)
+	if !ok {
+		return "", false
+	}
+	if !strings.HasPrefix(http.DetectContentType(data), "image/") {
+		return "", false
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", false
+	}
+	return fence + "\n" + imageToASCII(img, imageWidth) + "\n" + fence, true
+}
+
+// findImageData decodes the first data URI in content, or the whole content
+// when it's nothing but base64.
+func findImageData(content string) ([]byte, bool) {
+	encoded := strings.TrimSpace(content)
+	if start := strings.Index(content, "data:image/"); start != -1 {
+		marker := strings.Index(content[start:], ";base64,")
+		if marker == -1 {
+			return nil, false
+		}
+		encoded = content[start+marker+len(";base64,"):]
+		if end := strings.IndexAny(encoded, " \n\t\")'>`"); end != -1 {
+			encoded = encoded[:end]
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	return data, true
+}
+
+// imageChars returns the characters pixels are drawn with, from the darkest
+// to the lightest. ASCII_IMAGE_RAMP swaps in a set of its own, e.g. a denser
+// one, and ASCII_IMAGE_INVERT=true reverses it for art shown light on dark.
+// An empty ramp can't draw anything, so imageRamp is used instead.
+func imageChars() []rune {
+	chars := []rune(os.Getenv("ASCII_IMAGE_RAMP"))
+	if len(chars) == 0 {
+		chars = []rune(imageRamp)
+	}
+	if envBool("ASCII_IMAGE_INVERT", false) {
+		slices.Reverse(chars)
+	}
+	return chars
+}
+
+// imageToASCII draws an image width characters wide, picking each character
+// by the brightness of the pixel it covers. Rows are sampled further apart
+// than columns to make up for cells being taller than they're wide.
+func imageToASCII(img image.Image, width int) string {
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 || width < 1 {
+		return ""
+	}
+	height := imageRows(bounds.Dx(), bounds.Dy(), width)
+	ramp := imageChars()
+	lines := make([]string, 0, height)
+	for row := 0; row < height; row++ {
+		y := bounds.Min.Y + row*bounds.Dy()/height
+		var line strings.Builder
+		for col := 0; col < width; col++ {
+			x := bounds.Min.X + col*bounds.Dx()/width
+			gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+			line.WriteRune(ramp[int(gray.Y)*(len(ramp)-1)/255])
+		}
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+	return strings.Join(lines, "\n")
+}
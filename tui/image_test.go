@@ -0,0 +1,95 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// grayImage returns an image two pixels tall with a column for each gray
+// value, so it converts to a single row at its own width.
+func grayImage(values ...uint8) image.Image {
+	img := image.NewGray(image.Rect(0, 0, len(values), 2))
+	for x, v := range values {
+		img.SetGray(x, 0, color.Gray{Y: v})
+		img.SetGray(x, 1, color.Gray{Y: v})
+	}
+	return img
+}
+
+func TestImageRamp(t *testing.T) {
+	tests := []struct {
+		name   string
+		ramp   string
+		invert string
+		values []uint8
+		want   string
+	}{
+		{name: "default", values: []uint8{0, 29, 57, 128, 227, 255, 0}, want: "@%#+. @"},
+		{name: "inverted", invert: "true", values: []uint8{255, 0, 128}, want: "@ ="},
+		{name: "custom", ramp: "ab", values: []uint8{0, 127, 128, 255}, want: "aaab"},
+		{name: "dense", ramp: "█▓▒░ ", values: []uint8{0, 64, 128, 192, 255, 0}, want: "█▓▒░ █"},
+		{name: "empty falls back to default", ramp: "", values: []uint8{0, 128}, want: "@+"},
+		{name: "trailing light pixels trimmed", values: []uint8{0, 255, 255}, want: "@"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASCII_IMAGE_RAMP", tt.ramp)
+			t.Setenv("ASCII_IMAGE_INVERT", tt.invert)
+			t.Setenv("ASCII_IMAGE_ASPECT", "")
+			img := grayImage(tt.values...)
+			if got := imageToASCII(img, len(tt.values)); got != tt.want {
+				t.Errorf("imageToASCII() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		aspect string
+		dx, dy int
+		width  int
+		want   int
+	}{
+		{name: "square at default aspect", dx: 100, dy: 100, width: 80, want: 40},
+		{name: "wide image", dx: 200, dy: 100, width: 80, want: 20},
+		{name: "tall image", dx: 100, dy: 300, width: 40, want: 60},
+		{name: "rounds to nearest", dx: 100, dy: 33, width: 10, want: 2},
+		{name: "configured aspect", aspect: "1", dx: 100, dy: 100, width: 80, want: 80},
+		{name: "zero falls back to default", aspect: "0", dx: 100, dy: 100, width: 80, want: 40},
+		{name: "negative falls back to default", aspect: "-2", dx: 100, dy: 100, width: 80, want: 40},
+		{name: "at least one row", dx: 1000, dy: 1, width: 10, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASCII_IMAGE_ASPECT", tt.aspect)
+			if got := imageRows(tt.dx, tt.dy, tt.width); got != tt.want {
+				t.Errorf("imageRows(%d, %d, %d) = %d, want %d", tt.dx, tt.dy, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageToASCIISamplesRows(t *testing.T) {
+	t.Setenv("ASCII_IMAGE_RAMP", "")
+	t.Setenv("ASCII_IMAGE_INVERT", "")
+	t.Setenv("ASCII_IMAGE_ASPECT", "")
+	// One column, four rows dark to light; at 0.5 only rows 0 and 2 are
+	// sampled.
+	img := image.NewGray(image.Rect(0, 0, 1, 4))
+	for y, v := range []uint8{0, 85, 170, 255} {
+		img.SetGray(0, y, color.Gray{Y: v})
+	}
+	if got, want := imageToASCII(img, 1), "@\n-"; got != want {
+		t.Errorf("imageToASCII() = %q, want %q", got, want)
+	}
+	t.Setenv("ASCII_IMAGE_ASPECT", "1")
+	if got, want := imageToASCII(img, 1), "@\n*\n-\n"; got != want {
+		t.Errorf("imageToASCII() at aspect 1 = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// improvePrompt asks the model to refine art that was loaded as context.
+const improvePrompt = "Improve the ascii art I gave you. Keep the same subject and roughly the same size, but clean up the lines, fix the proportions and add detail where it helps. Put the improved art in a code block."
+
+var (
+	removedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	addedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+)
+
+// loadArtFile reads art to improve, with any fence it was saved with removed.
+func loadArtFile(path string) (string, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Files usually end in a newline after the closing fence
+	art := strings.Trim(stripFence(strings.TrimRight(strings.ReplaceAll(string(text), "\r\n", "\n"), "\n")), "\n")
+	if strings.TrimSpace(art) == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return art, nil
+}
+
+// improveArt loads the art at path as context and asks for it to be refined.
+// The original is kept to compare the answer against.
+func (m *chatModel) improveArt(path string) tea.Cmd {
+	art, err := loadArtFile(strings.TrimSpace(path))
+	if err != nil {
+		m.messages = append(m.messages, m.senderStyle.Render("Could not load art to improve: "+err.Error()))
+		m.refresh()
+		return nil
+	}
+	m.history = withArtContext(m.history, art)
+	m.messages = append(m.messages, m.senderStyle.Render(userLabel()+" (art to improve):")+"\n"+art)
+	m.original = art
+	return m.send(improvePrompt)
+}
+
+// artDiff compares two pieces of art line by line, marking the lines that
+// were taken out with - and the ones put in with +.
+func artDiff(before string, after string) string {
+	a := strings.Split(strings.Trim(stripFence(before), "\n"), "\n")
+	b := strings.Split(strings.Trim(stripFence(after), "\n"), "\n")
+	// Longest common subsequence of lines, filled in from the end
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	lines := []string{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, removedStyle.Render("- "+a[i]))
+			i++
+		default:
+			lines = append(lines, addedStyle.Render("+ "+b[j]))
+			j++
+		}
+	}
+	return strings.Join(lines, "\n")
+}
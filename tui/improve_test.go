@@ -0,0 +1,100 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// artFile writes text to a file in a temporary directory and returns its path.
+func artFile(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "art.txt")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadArtFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr string
+	}{
+		{name: "plain", text: "/\\\n\\/\n", want: "/\\\n\\/"},
+		{name: "fenced with windows newlines", text: "```\r\n/\\\r\n\\/\r\n```\r\n", want: "/\\\n\\/"},
+		{name: "empty", text: "```\n  \n```", wantErr: "is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadArtFile(artFile(t, tt.text))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadArtFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("loadArtFile() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := loadArtFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("loadArtFile() of a missing file didn't fail")
+	}
+}
+
+func TestArtDiff(t *testing.T) {
+	got := ansi.Strip(artDiff("```\na\nb\nc\n```", "a\nB\nc\nd"))
+	if want := "  a\n- b\n+ B\n  c\n+ d"; got != want {
+		t.Errorf("artDiff() = %q, want %q", got, want)
+	}
+}
+
+func TestChatImproveArt(t *testing.T) {
+	client := answering("Better:\n```\n/\\\n||\n\\/\n```")
+	m := newTestChat(t, client)
+	path := artFile(t, "```\n/\\\n\\/\n```\n")
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i"), Alt: true})
+	m = sendThrough(t, next.(chatModel), path)
+
+	sent := client.sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(sent))
+	}
+	messages := sent[0].Messages
+	if len(messages) < 2 || !strings.Contains(messages[len(messages)-2].Content, "existing ascii art to use as context for my next message:\n```\n/\\\n\\/\n```") || strings.Count(messages[len(messages)-2].Content, fence) != 2 {
+		t.Errorf("sent %+v, want the art from the file as context", messages)
+	}
+	if messages[len(messages)-1].Content != improvePrompt {
+		t.Errorf("last message = %q, want the improve prompt", messages[len(messages)-1].Content)
+	}
+	if view := ansi.Strip(strings.Join(m.messages, "\n")); !strings.Contains(view, "Changes from your art:\n  /\\\n+ ||\n  \\/") {
+		t.Errorf("messages don't show the changes:\n%s", view)
+	}
+}
+
+func TestChatImproveMissingFile(t *testing.T) {
+	client := answering("unused")
+	m := newTestChat(t, client)
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i"), Alt: true})
+	m = next.(chatModel)
+	m.textarea.SetValue(filepath.Join(t.TempDir(), "missing.txt"))
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(chatModel)
+	if cmd != nil || len(client.sent()) != 0 {
+		t.Error("sent a request without the art")
+	}
+	if last := m.messages[len(m.messages)-1]; !strings.Contains(last, "Could not load art to improve") {
+		t.Errorf("last message = %q, want the load error", last)
+	}
+}
@@ -0,0 +1,45 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// insertTokens returns the snippets alt+1 to alt+9 type into the message box,
+// set by a comma separated ASCII_INSERTS. {date} and {year} in them are filled
+// in when they're inserted, so a signature like "by Eric {year}" stays
+// current.
+func insertTokens() []string {
+	tokens := []string{}
+	for _, token := range strings.Split(os.Getenv("ASCII_INSERTS"), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) == 0 {
+		return []string{"{date}", "{year}"}
+	}
+	return tokens
+}
+
+// expandToken fills in the placeholders in token for the time now.
+func expandToken(token string, now time.Time) string {
+	return strings.NewReplacer(
+		"{date}", now.Format("January 2, 2006"),
+		"{year}", strconv.Itoa(now.Year()),
+	).Replace(token)
+}
+
+// insertKey returns which token an alt+number key inserts, counting from 0.
+func insertKey(key string) (int, bool) {
+	if len(key) != len("alt+1") || !strings.HasPrefix(key, "alt+") {
+		return 0, false
+	}
+	n := int(key[4] - '1')
+	return n, n >= 0 && n < 9
+}
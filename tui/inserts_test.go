@@ -0,0 +1,71 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestInsertTokens(t *testing.T) {
+	t.Setenv("ASCII_INSERTS", "")
+	if got := insertTokens(); !slices.Equal(got, []string{"{date}", "{year}"}) {
+		t.Errorf("insertTokens() = %q, want the defaults", got)
+	}
+	t.Setenv("ASCII_INSERTS", " by Eric {year} ,, Happy New Year ")
+	if got := insertTokens(); !slices.Equal(got, []string{"by Eric {year}", "Happy New Year"}) {
+		t.Errorf("insertTokens() = %q, want the configured ones", got)
+	}
+}
+
+func TestExpandToken(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if got := expandToken("{date} / {year} / {other}", now); got != "January 1, 2025 / 2025 / {other}" {
+		t.Errorf("expandToken() = %q", got)
+	}
+}
+
+func TestInsertKey(t *testing.T) {
+	for key, want := range map[string]int{"alt+1": 0, "alt+9": 8} {
+		if got, ok := insertKey(key); !ok || got != want {
+			t.Errorf("insertKey(%q) = %d, %v, want %d", key, got, ok, want)
+		}
+	}
+	for _, key := range []string{"alt+0", "alt+a", "1", "ctrl+1", "alt+10"} {
+		if _, ok := insertKey(key); ok {
+			t.Errorf("insertKey(%q) inserts, want it left alone", key)
+		}
+	}
+}
+
+func TestChatInsertAtCursor(t *testing.T) {
+	m := newTestChat(t, answering("unused"))
+	t.Setenv("ASCII_INSERTS", "{year},sig")
+	m.textarea.SetValue("Happy New Year !")
+	m.textarea.SetCursor(len("Happy New Year "))
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("1"), Alt: true})
+	m = next.(chatModel)
+	year := strconv.Itoa(time.Now().Year())
+	if got := m.textarea.Value(); got != "Happy New Year "+year+"!" {
+		t.Errorf("value = %q, want the year inserted at the cursor", got)
+	}
+	if col := m.textarea.LineInfo().ColumnOffset; col != len("Happy New Year "+year) {
+		t.Errorf("cursor at %d, want it after the inserted year", col)
+	}
+
+	// Tokens that don't fit, or aren't configured, aren't typed
+	m.textarea.SetValue(strings.Repeat("x", promptLimit-2))
+	for _, key := range []string{"2", "3"} {
+		next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key), Alt: true})
+		m = next.(chatModel)
+	}
+	if got := m.textarea.Value(); got != strings.Repeat("x", promptLimit-2) {
+		t.Errorf("value = %q, want nothing inserted", got)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/exp/teatest"
+)
+
+// startChat runs the chat in a test program, sending requests with client.
+func startChat(t *testing.T, client ChatClient) *teatest.TestModel {
+	t.Helper()
+	testEnv(t)
+	tm := teatest.NewTestModel(t, NewChatModel().WithClient(client), teatest.WithInitialTermSize(100, 40))
+	t.Cleanup(func() { tm.Quit() })
+	return tm
+}
+
+// waitForAll waits until the output so far contains every one of want.
+func waitForAll(t *testing.T, tm *teatest.TestModel, want ...string) {
+	t.Helper()
+	teatest.WaitFor(t, tm.Output(), func(out []byte) bool {
+		for _, w := range want {
+			if !bytes.Contains(out, []byte(w)) {
+				return false
+			}
+		}
+		return true
+	}, teatest.WithDuration(5*time.Second))
+}
+
+// prompt types a message into the chat and sends it.
+func prompt(tm *teatest.TestModel, text string) {
+	tm.Type(text)
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+func TestChatFlow(t *testing.T) {
+	tm := startChat(t, answering("Here's a cat:\n```\n/\\_/\\\n( o.o )\n```"))
+	prompt(tm, "draw a cat")
+	waitForAll(t, tm, "You: draw a cat", "ChatGPT: Here's a cat:", "( o.o )")
+}
+
+func TestChatOffline(t *testing.T) {
+	t.Setenv("RETRY_ENABLED", "false")
+	t.Setenv("ASCII_RECONNECT_SECONDS", "0")
+	unreachable := &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "api.openai.com"}}
+	tm := startChat(t, failing(unreachable))
+	prompt(tm, "draw a cat")
+	waitForAll(t, tm, "Couldn't reach OpenAI", "offline — showing example art")
+	// Example art stands in until the connection is back
+	prompt(tm, "draw a dog")
+	waitForAll(t, tm, "MISSING", "Would you like to save this art?")
+}
+
+func TestChatResize(t *testing.T) {
+	tm := startChat(t, answering("ok"))
+	tm.Send(tea.WindowSizeMsg{Width: 60, Height: 30})
+	tm.Quit()
+	m := tm.FinalModel(t, teatest.WithFinalTimeout(5*time.Second)).(chatModel)
+	if m.width != 60 || m.height != 30 {
+		t.Errorf("size = %dx%d, want 60x30", m.width, m.height)
+	}
+	if want := 60 - 2*m.padding; m.viewport.Width != want {
+		t.Errorf("viewport width = %d, want %d", m.viewport.Width, want)
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// jsonInstruction describes the JSON asked for in JSON mode. JSON mode needs
+// the word JSON to be somewhere in the messages as well.
+const jsonInstruction = `Reply with a JSON object with two string fields. "art" is the ascii art on its own, without code fences, or "" if there isn't any. "message" is anything else you'd like to say.`
+
+// artResponse is the shape of a reply in JSON mode.
+type artResponse struct {
+	Art     string `json:"art"`
+	Message string `json:"message"`
+}
+
+// jsonMode reports whether replies are asked for as JSON objects instead of
+// markdown, with ASCII_JSON_MODE. Models that can't do it get markdown anyway.
+func jsonMode() bool {
+	return envBool("ASCII_JSON_MODE", false)
+}
+
+// withJSONMode asks for the reply to req as a JSON object with the art in its
+// own field, so it doesn't have to be picked out of markdown.
+func withJSONMode(req openai.ChatCompletionRequest) openai.ChatCompletionRequest {
+	req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	req.Messages = append([]openai.ChatCompletionMessage{{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: jsonInstruction,
+	}}, req.Messages...)
+	return req
+}
+
+// fromJSONResponse turns a reply in JSON mode back into the text the chat
+// expects, the message followed by the art in a fence. Anything that isn't
+// the JSON asked for is returned as it is, for the fences to be looked for.
+func fromJSONResponse(text string) string {
+	var reply artResponse
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &reply); err != nil {
+		return text
+	}
+	parts := []string{}
+	if message := strings.TrimSpace(reply.Message); message != "" {
+		parts = append(parts, message)
+	}
+	if art := strings.Trim(reply.Art, "\n"); strings.TrimSpace(art) != "" {
+		parts = append(parts, fence+"\n"+art+"\n"+fence)
+	}
+	if len(parts) == 0 {
+		return text
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestFromJSONResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "message and art",
+			text: `{"art": "/\\_/\\\n( o.o )", "message": "Here's a cat."}`,
+			want: "Here's a cat.\n\n```\n/\\_/\\\n( o.o )\n```",
+		},
+		{
+			name: "art only, with surrounding newlines trimmed",
+			text: "  {\"art\": \"\\n(o.o)\\n\", \"message\": \"\"}\n",
+			want: "```\n(o.o)\n```",
+		},
+		{
+			name: "message only",
+			text: `{"art": "", "message": "What kind of cat?"}`,
+			want: "What kind of cat?",
+		},
+		{
+			name: "indentation in the art kept",
+			text: `{"art": "  /\\\n /  \\\n/____\\"}`,
+			want: "```\n  /\\\n /  \\\n/____\\\n```",
+		},
+		{
+			name: "markdown falls back",
+			text: "Here:\n```\n(o.o)\n```",
+			want: "Here:\n```\n(o.o)\n```",
+		},
+		{
+			name: "empty object falls back",
+			text: `{}`,
+			want: `{}`,
+		},
+		{
+			name: "blank art falls back",
+			text: `{"art": "   "}`,
+			want: `{"art": "   "}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fromJSONResponse(tt.text); got != tt.want {
+				t.Errorf("fromJSONResponse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONModeGenerate(t *testing.T) {
+	testEnv(t)
+	t.Setenv("ASCII_JSON_MODE", "true")
+	client := answering(`{"art": "(o.o)\n/| |\\", "message": "A face."}`)
+	gen, err := Generate(client, "a face")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen.Art != "(o.o)\n/| |\\" {
+		t.Errorf("art = %q, want it parsed from the JSON", gen.Art)
+	}
+	sent := client.sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(sent))
+	}
+	if format := sent[0].ResponseFormat; format == nil || format.Type != openai.ChatCompletionResponseFormatTypeJSONObject {
+		t.Errorf("response format = %+v, want a JSON object", format)
+	}
+	if first := sent[0].Messages[0]; first.Role != openai.ChatMessageRoleSystem || first.Content != jsonInstruction {
+		t.Errorf("first message = %+v, want the JSON instruction", first)
+	}
+}
+
+func TestJSONModeFallsBack(t *testing.T) {
+	testEnv(t)
+	t.Setenv("ASCII_JSON_MODE", "true")
+	// gpt-4 can't be held to JSON, so it's asked without the response format
+	// and its markdown is searched for fences
+	t.Setenv("ASCII_MODELS", "gpt-4")
+	client := answering("Here:\n```\n(o.o)\n```")
+	gen, err := Generate(client, "a face")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen.Art != "(o.o)" {
+		t.Errorf("art = %q, want it found in the fences", gen.Art)
+	}
+	if sent := client.sent(); len(sent) != 1 || sent[0].ResponseFormat != nil {
+		t.Errorf("sent %+v, want no response format for gpt-4", sent)
+	}
+}
+
+func TestJSONModeOff(t *testing.T) {
+	testEnv(t)
+	client := answering("```\n(o.o)\n```")
+	if _, err := Generate(client, "a face"); err != nil {
+		t.Fatal(err)
+	}
+	sent := client.sent()
+	if len(sent) != 1 || sent[0].ResponseFormat != nil || sent[0].Messages[0].Content == jsonInstruction {
+		t.Errorf("sent %+v, want a plain markdown request", sent)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// apiKeys returns the openai api keys to spread requests across, a comma
+// separated OPENAI_API_KEYS, or OPENAI_API_KEY on its own when that's empty.
+func apiKeys() []string {
+	keys := []string{}
+	for _, key := range strings.Split(os.Getenv("OPENAI_API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// keyName identifies a key in the log without giving it away.
+func keyName(key string) string {
+	if len(key) <= 4 {
+		return "…"
+	}
+	return "…" + key[len(key)-4:]
+}
+
+// rotatingClient sends each request with the next key in turn, and moves on
+// to the one after when a key is rate limited, until they've all been tried.
+type rotatingClient struct {
+	clients []StreamingClient
+	keys    []string
+	next    *atomic.Uint64
+}
+
+func newRotatingClient(keys []string, newClient func(key string) StreamingClient) rotatingClient {
+	clients := make([]StreamingClient, len(keys))
+	for i, key := range keys {
+		clients[i] = newClient(key)
+	}
+	return rotatingClient{clients: clients, keys: keys, next: &atomic.Uint64{}}
+}
+
+// try calls send with each key from the next one on, stopping at the first
+// that isn't rate limited.
+func (c rotatingClient) try(send func(client StreamingClient) error) error {
+	start := c.next.Add(1) - 1
+	var err error
+	for n := range c.clients {
+		i := int((start + uint64(n)) % uint64(len(c.clients)))
+		debugf("sending with api key %d of %d (%s)", i+1, len(c.keys), keyName(c.keys[i]))
+		if err = send(c.clients[i]); !tooManyRequests(err) {
+			return err
+		}
+		debugf("api key %s is rate limited", keyName(c.keys[i]))
+	}
+	return err
+}
+
+func (c rotatingClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	var resp openai.ChatCompletionResponse
+	err := c.try(func(client StreamingClient) error {
+		var err error
+		resp, err = client.CreateChatCompletion(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (c rotatingClient) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionStream, error) {
+	var stream *openai.ChatCompletionStream
+	err := c.try(func(client StreamingClient) error {
+		var err error
+		stream, err = client.CreateChatCompletionStream(ctx, req)
+		return err
+	})
+	return stream, err
+}
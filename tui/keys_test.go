@@ -0,0 +1,143 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestAPIKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+		key  string
+		want []string
+	}{
+		{name: "list", keys: "sk-one, sk-two,,sk-three ", key: "sk-single", want: []string{"sk-one", "sk-two", "sk-three"}},
+		{name: "falls back to the single key", keys: " , ", key: "sk-single", want: []string{"sk-single"}},
+		{name: "none", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPENAI_API_KEYS", tt.keys)
+			t.Setenv("OPENAI_API_KEY", tt.key)
+			if got := apiKeys(); !slices.Equal(got, tt.want) {
+				t.Errorf("apiKeys() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyName(t *testing.T) {
+	for key, want := range map[string]string{
+		"sk-abcdef1234": "…1234",
+		"1234":          "…",
+		"":              "…",
+	} {
+		if got := keyName(key); got != want {
+			t.Errorf("keyName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+// keyServer is an openai api that rate limits the keys it's given, and
+// keeps the key each request came with.
+func keyServer(t *testing.T, limited ...string) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	seen := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		mu.Lock()
+		seen = append(seen, key)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if slices.Contains(limited, key) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"message": "Rate limit reached", "type": "requests", "code": "rate_limit_exceeded"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{
+				Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "answered with " + key},
+				FinishReason: openai.FinishReasonStop,
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(seen)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	testEnv(t)
+	server, seen := keyServer(t, "sk-limited")
+	t.Setenv("OPENAI_BASE_URL", server.URL+"/v1")
+	t.Setenv("OPENAI_API_KEYS", "sk-one,sk-limited,sk-three")
+	client := NewChatClient()
+
+	// Each request starts on the next key, and the rate limited one is passed
+	// over for the key after it
+	answers := []string{}
+	for range 4 {
+		resp, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Model: "gpt-4o-mini"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		answers = append(answers, resp.Choices[0].Message.Content)
+	}
+	if want := []string{"sk-one", "sk-limited", "sk-three", "sk-three", "sk-one"}; !slices.Equal(seen(), want) {
+		t.Errorf("sent with %q, want %q", seen(), want)
+	}
+	want := []string{"answered with sk-one", "answered with sk-three", "answered with sk-three", "answered with sk-one"}
+	if !slices.Equal(answers, want) {
+		t.Errorf("answers = %q, want %q", answers, want)
+	}
+}
+
+func TestKeyRotationAllLimited(t *testing.T) {
+	testEnv(t)
+	server, seen := keyServer(t, "sk-one", "sk-two")
+	t.Setenv("OPENAI_BASE_URL", server.URL+"/v1")
+	t.Setenv("OPENAI_API_KEYS", "sk-one,sk-two")
+	client := NewChatClient()
+
+	_, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Model: "gpt-4o-mini"})
+	if !tooManyRequests(err) {
+		t.Errorf("err = %v, want the rate limit once every key has been tried", err)
+	}
+	if want := []string{"sk-one", "sk-two"}; !slices.Equal(seen(), want) {
+		t.Errorf("sent with %q, want each key tried once", seen())
+	}
+}
+
+func TestKeyRotationLogged(t *testing.T) {
+	path := debugLog(t)
+	server, _ := keyServer(t, "sk-limited")
+	t.Setenv("OPENAI_BASE_URL", server.URL+"/v1")
+	t.Setenv("OPENAI_API_KEYS", "sk-limited,sk-second")
+	if _, err := NewChatClient().CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Model: "gpt-4o-mini"}); err != nil {
+		t.Fatal(err)
+	}
+	log := readLog(t, path)
+	for _, want := range []string{"sending with api key 1 of 2 (…ited)", "api key …ited is rate limited", "sending with api key 2 of 2 (…cond)"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log doesn't have %q:\n%s", want, log)
+		}
+	}
+	if strings.Contains(log, "sk-limited") || strings.Contains(log, "sk-second") {
+		t.Errorf("log gives a key away:\n%s", log)
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"strings"
+)
+
+// providerLabels are what the assistant is called for each provider.
+var providerLabels = map[string]string{
+	"openai":    "ChatGPT",
+	"anthropic": "Claude",
+	"ollama":    "Ollama",
+}
+
+// provider returns which backend answers, set by ASCII_PROVIDER or guessed
+// from the OPENAI_BASE_URL it's reached at. It's openai unless either says
+// otherwise.
+func provider() string {
+	if p := os.Getenv("ASCII_PROVIDER"); p != "" {
+		return strings.ToLower(strings.TrimSpace(p))
+	}
+	url := strings.ToLower(os.Getenv("OPENAI_BASE_URL"))
+	switch {
+	case strings.Contains(url, "anthropic"):
+		return "anthropic"
+	case strings.Contains(url, "ollama"), strings.Contains(url, ":11434"):
+		return "ollama"
+	}
+	return "openai"
+}
+
+// assistantLabel is the name responses are shown under, ASCII_ASSISTANT_LABEL
+// or the name of the provider.
+func assistantLabel() string {
+	if label := os.Getenv("ASCII_ASSISTANT_LABEL"); label != "" {
+		return label
+	}
+	if label, ok := providerLabels[provider()]; ok {
+		return label
+	}
+	return provider()
+}
+
+// userLabel is the name prompts are shown under, ASCII_USER_LABEL or "You".
+func userLabel() string {
+	if label := os.Getenv("ASCII_USER_LABEL"); label != "" {
+		return label
+	}
+	return "You"
+}
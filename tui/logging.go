@@ -0,0 +1,70 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// logRequest writes a line about a finished request to the ASCII_DEBUG_LOG
+// file. Prompts and responses can be sensitive, so by default they're only
+// described by their length and a short hash, which is enough to tell
+// requests apart. ASCII_LOG_BODIES=true logs them in full.
+func logRequest(req openai.ChatCompletionRequest, c completion, err error, elapsed time.Duration) {
+	if os.Getenv("ASCII_DEBUG_LOG") == "" {
+		return
+	}
+	prompt := ""
+	if len(req.Messages) > 0 {
+		prompt = req.Messages[len(req.Messages)-1].Content
+	}
+	line := fmt.Sprintf("request model=%s messages=%d elapsed=%s", req.Model, len(req.Messages), elapsed.Round(time.Millisecond))
+	if err != nil {
+		line += fmt.Sprintf(" error=%q", err.Error())
+	} else {
+		line += fmt.Sprintf(" tokens=%d/%d finish=%s", c.PromptTokens, c.CompletionTokens, c.FinishReason)
+	}
+	line += " prompt=" + redact(prompt)
+	if err == nil {
+		line += " response=" + redact(c.Text)
+	}
+	debugf("%s", line)
+}
+
+// debugf writes a line to the ASCII_DEBUG_LOG file, if there is one. It's
+// opened for each line so logging works inside and outside of the interface.
+func debugf(format string, args ...any) {
+	path := os.Getenv("ASCII_DEBUG_LOG")
+	if path == "" {
+		return
+	}
+	writes.Lock()
+	defer writes.Unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	log.New(f, "ascii ", log.LstdFlags).Printf(format, args...)
+}
+
+// redact describes body for the log, unless ASCII_LOG_BODIES asks for it to
+// be logged as is.
+func redact(body string) string {
+	if envBool("ASCII_LOG_BODIES", false) {
+		return fmt.Sprintf("%q", body)
+	}
+	if body == "" {
+		return "[empty]"
+	}
+	sum := sha256.Sum256([]byte(strings.TrimSpace(body)))
+	return fmt.Sprintf("[redacted %d chars sha256:%x]", len([]rune(body)), sum[:4])
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// debugLog sends the debug log to a temporary file and returns its path.
+func debugLog(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(testEnv(t), "debug.log")
+	t.Setenv("ASCII_DEBUG_LOG", path)
+	return path
+}
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRedact(t *testing.T) {
+	t.Setenv("ASCII_LOG_BODIES", "")
+	got := redact("my secret prompt")
+	if strings.Contains(got, "secret") || !strings.HasPrefix(got, "[redacted 16 chars sha256:") {
+		t.Errorf("redact() = %q, want only the length and a hash", got)
+	}
+	if again := redact("my secret prompt\n"); again[strings.Index(again, "sha256"):] != got[strings.Index(got, "sha256"):] {
+		t.Errorf("redact() hashes %q and %q, want the same hash for the same text", got, again)
+	}
+	if got := redact(""); got != "[empty]" {
+		t.Errorf("redact(\"\") = %q, want [empty]", got)
+	}
+	t.Setenv("ASCII_LOG_BODIES", "true")
+	if got := redact("my secret prompt"); got != `"my secret prompt"` {
+		t.Errorf("redact() = %q with ASCII_LOG_BODIES on, want the body", got)
+	}
+}
+
+func TestLogRequestRedactsBodies(t *testing.T) {
+	path := debugLog(t)
+	req := openai.ChatCompletionRequest{Model: "gpt-4o", Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "my secret prompt"}}}
+	logRequest(req, completion{Text: "secret art", PromptTokens: 3, CompletionTokens: 4, FinishReason: finishStop}, nil, 1500*time.Millisecond)
+	logRequest(req, completion{}, errors.New("bad request"), time.Second)
+	log := readLog(t, path)
+	if strings.Contains(log, "secret") {
+		t.Errorf("log has the bodies in it:\n%s", log)
+	}
+	for _, want := range []string{"model=gpt-4o messages=1 elapsed=1.5s tokens=3/4 finish=stop prompt=[redacted 16 chars", "response=[redacted 10 chars", `error="bad request"`} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log doesn't have %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestLogRequestWithBodies(t *testing.T) {
+	path := debugLog(t)
+	t.Setenv("ASCII_LOG_BODIES", "true")
+	req := openai.ChatCompletionRequest{Model: "gpt-4o", Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "draw a cat"}}}
+	logRequest(req, completion{Text: "(o.o)"}, nil, time.Second)
+	if log := readLog(t, path); !strings.Contains(log, `prompt="draw a cat" response="(o.o)"`) {
+		t.Errorf("log doesn't have the bodies:\n%s", log)
+	}
+}
+
+func TestLogRequestOff(t *testing.T) {
+	dir := testEnv(t)
+	logRequest(openai.ChatCompletionRequest{}, completion{}, nil, time.Second)
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("wrote %v without ASCII_DEBUG_LOG", entries)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+// reconnectMsg reports whether openai could be reached again.
+type reconnectMsg bool
+
+// disconnected reports whether a failed request couldn't get through to
+// openai at all, like when the network is down, as opposed to openai
+// answering with an error. Only a failed lookup or connection counts, a
+// timeout waiting on an answer means openai was reached.
+func disconnected(err error) bool {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	if errors.As(err, &apiErr) || errors.As(err, &reqErr) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// baseURL is where requests go, OPENAI_BASE_URL or openai's own api.
+func baseURL() string {
+	if url := os.Getenv("OPENAI_BASE_URL"); url != "" {
+		return url
+	}
+	return openai.DefaultConfig("").BaseURL
+}
+
+// reachable reports whether anything answers at url through client. Any
+// answer will do, even an error status, since it means the connection is
+// back.
+func reachable(client *http.Client, url string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+// reconnectTick checks whether openai can be reached again after
+// ASCII_RECONNECT_SECONDS, 30 by default. Zero or less never checks, leaving
+// the chat offline until it's restarted. The check goes to OPENAI_BASE_URL
+// the same way requests do, through any proxy and with any extra
+// certificates.
+func reconnectTick() tea.Cmd {
+	seconds := envInt("ASCII_RECONNECT_SECONDS", 30)
+	if seconds <= 0 {
+		return nil
+	}
+	return tea.Tick(time.Duration(seconds)*time.Second, func(time.Time) tea.Msg {
+		return reconnectMsg(reachable(newHTTPClient(), baseURL()))
+	})
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestDisconnected(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "dns", err: &net.DNSError{Err: "no such host", Name: "api.openai.com"}, want: true},
+		{name: "dial", err: dial, want: true},
+		{name: "dial in a url error", err: &url.Error{Op: "Post", URL: "https://api.openai.com", Err: dial}, want: true},
+		{name: "read", err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}},
+		{name: "timeout", err: &url.Error{Op: "Post", URL: "https://api.openai.com", Err: context.DeadlineExceeded}},
+		{name: "deadline", err: os.ErrDeadlineExceeded},
+		{name: "api error", err: &openai.APIError{HTTPStatusCode: http.StatusInternalServerError}},
+		{name: "request error", err: &openai.RequestError{HTTPStatusCode: http.StatusBadGateway, Err: dial}},
+		{name: "other", err: errors.New("bad request")},
+		{name: "wrapped", err: fmt.Errorf("sending: %w", dial), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := disconnected(tt.err); got != tt.want {
+				t.Errorf("disconnected(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Turned away, but reached
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	if !reachable(server.Client(), server.URL+"/v1") {
+		t.Errorf("reachable() = false for a server that answers")
+	}
+	server.Close()
+	if reachable(server.Client(), server.URL+"/v1") {
+		t.Errorf("reachable() = true for a closed server")
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	t.Setenv("OPENAI_BASE_URL", "")
+	if got := baseURL(); !strings.HasPrefix(got, "https://api.openai.com") {
+		t.Errorf("baseURL() = %q, want openai's", got)
+	}
+	t.Setenv("OPENAI_BASE_URL", "http://localhost:11434/v1")
+	if got := baseURL(); got != "http://localhost:11434/v1" {
+		t.Errorf("baseURL() = %q, want OPENAI_BASE_URL", got)
+	}
+}
+
+func TestChatGoesOfflineOnlyWhenUnreachable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unreachable", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: true},
+		{name: "timeout", err: &url.Error{Op: "Post", URL: "https://api.openai.com", Err: context.DeadlineExceeded}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestChat(t, failing(tt.err))
+			t.Setenv("RETRY_ENABLED", "false")
+			m.textarea.SetValue("a cat")
+			next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			next, _ = next.Update(awaitMsg[responseMsg](t, cmd))
+			if got := next.(chatModel).offline; got != tt.want {
+				t.Errorf("offline = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// paletteColumns is how many swatches fit on each row of the color picker.
+const paletteColumns = 8
+
+// palette returns the colors the picker offers, starting with "" for the
+// terminal's default. Terminals limited to 16 colors only get those, and ones
+// without color support get none.
+func palette() []string {
+	profile := lipgloss.ColorProfile()
+	if profile == termenv.Ascii {
+		return nil
+	}
+	colors := []string{""}
+	for i := 1; i < 16; i++ {
+		colors = append(colors, strconv.Itoa(i))
+	}
+	if profile != termenv.ANSI {
+		// A spread of the 256 color cube
+		for _, c := range []int{0, 16, 52, 88, 124, 160, 196, 202, 208, 214, 220, 226, 190, 118, 46, 48, 51, 45, 39, 33, 27, 21, 57, 93, 129, 165, 201, 199, 244, 250, 255} {
+			colors = append(colors, strconv.Itoa(c))
+		}
+	}
+	return colors
+}
+
+// colorArt renders art without its fences in the given colors. An empty color
+// leaves the terminal's own.
+func colorArt(art string, foreground string, background string) string {
+	style := lipgloss.NewStyle()
+	if foreground != "" {
+		style = style.Foreground(lipgloss.Color(foreground))
+	}
+	if background != "" {
+		style = style.Background(lipgloss.Color(background))
+	}
+	lines := strings.Split(stripFence(art), "\n")
+	width := 0
+	for _, line := range lines {
+		width = max(width, lipgloss.Width(line))
+	}
+	for i, line := range lines {
+		// Pad so the background covers a rectangle
+		lines[i] = style.Render(line + strings.Repeat(" ", width-lipgloss.Width(line)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// paletteGrid renders the picker's swatches, with the one at cursor marked.
+func paletteGrid(colors []string, cursor int) string {
+	rows := []string{}
+	row := []string{}
+	for i, c := range colors {
+		swatch := lipgloss.NewStyle().Background(lipgloss.Color(c)).Render("    ")
+		if c == "" {
+			swatch = " -- "
+		}
+		mark := " "
+		if i == cursor {
+			mark = ">"
+		}
+		row = append(row, mark+swatch)
+		if len(row) == paletteColumns {
+			rows = append(rows, strings.Join(row, " "))
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, strings.Join(row, " "))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// artColors returns the colors art is shown in, kept with the chat so they
+// last the session.
+func (m questionModel) artColors() (string, string) {
+	if m.chat != nil {
+		return m.chat.artForeground, m.chat.artBackground
+	}
+	return m.foreground, m.background
+}
+
+// setArtColors keeps the chosen colors for the rest of the session.
+func (m *questionModel) setArtColors(foreground string, background string) {
+	if m.chat != nil {
+		m.chat.artForeground, m.chat.artBackground = foreground, background
+		return
+	}
+	m.foreground, m.background = foreground, background
+}
+
+// pickedColors returns the art's colors with the one being picked swapped
+// for the color under the cursor, for the live preview.
+func (m questionModel) pickedColors() (string, string) {
+	foreground, background := m.artColors()
+	colors := palette()
+	if m.pickIndex < len(colors) {
+		if m.pickBackground {
+			background = colors[m.pickIndex]
+		} else {
+			foreground = colors[m.pickIndex]
+		}
+	}
+	return foreground, background
+}
+
+// updatePicker handles keys while the color picker is open.
+func (m questionModel) updatePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	colors := palette()
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.picking = false
+	case "tab":
+		m.pickBackground = !m.pickBackground
+	case "left", "h":
+		m.pickIndex = max(0, m.pickIndex-1)
+	case "right", "l":
+		m.pickIndex = min(len(colors)-1, m.pickIndex+1)
+	case "up", "k":
+		m.pickIndex = max(0, m.pickIndex-paletteColumns)
+	case "down", "j":
+		m.pickIndex = min(len(colors)-1, m.pickIndex+paletteColumns)
+	case "enter":
+		m.setArtColors(m.pickedColors())
+		m.picking = false
+	}
+	return m, nil
+}
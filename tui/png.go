@@ -0,0 +1,77 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Measurements of a cell in the exported PNG, in pixels of the 7x13 font it's
+// drawn in.
+const (
+	pngCellWidth  = 7
+	pngLineHeight = 13
+	pngPadding    = 13
+)
+
+// PNG renders art as an image in a fixed 7x13 pixel font, with the canvas
+// sized to the widest line like the SVG. Each character is drawn in its own
+// cell so the art keeps its shape, and those the font doesn't have, past
+// Latin-1, come out as boxes. The colors are hex, like #1e1e1e.
+func PNG(art string, foreground string, background string) ([]byte, error) {
+	fg, err := hexColor(foreground)
+	if err != nil {
+		return nil, err
+	}
+	bg, err := hexColor(background)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(plainArt(art), "\n")
+	cols := 0
+	for _, line := range lines {
+		cols = max(cols, runewidth.StringWidth(line))
+	}
+	img := image.NewRGBA(image.Rect(0, 0, cols*pngCellWidth+2*pngPadding, len(lines)*pngLineHeight+2*pngPadding))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	d := font.Drawer{Dst: img, Src: image.NewUniform(fg), Face: basicfont.Face7x13}
+	for i, line := range lines {
+		col := 0
+		for _, r := range line {
+			d.Dot = fixed.P(pngPadding+col*pngCellWidth, pngPadding+i*pngLineHeight+basicfont.Face7x13.Ascent)
+			d.DrawString(string(r))
+			col += runewidth.RuneWidth(r)
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// hexColor reads a color written as #rgb or #rrggbb.
+func hexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return color.RGBA{}, fmt.Errorf("%q isn't a hex color like #1e1e1e", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// tokenBucket spaces requests out to rate a second, letting up to capacity of
+// them through at once after a quiet spell.
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+}
+
+func newTokenBucket(perMinute int, capacity int) *tokenBucket {
+	return &tokenBucket{
+		rate:     float64(perMinute) / 60,
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+		last:     time.Now(),
+		now:      time.Now,
+	}
+}
+
+// refill adds the tokens earned since the last call. The caller holds mu.
+func (b *tokenBucket) refill() {
+	now := b.now()
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// reserve takes a token and returns how long to wait before using it. Tokens
+// can be taken before they're earned, which queues requests up behind each
+// other.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// pending returns how long a request made now would be held for, without
+// taking a token.
+func (b *tokenBucket) pending() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// wait blocks until a request can be sent, or ctx is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	d := b.reserve()
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var (
+	sendLimiterMu     sync.Mutex
+	sendLimiter       *tokenBucket
+	sendLimiterLoaded bool
+)
+
+// limiter returns the bucket every request to openai shares, allowing
+// ASCII_RATE_LIMIT requests a minute (default 60) in bursts of up to
+// ASCII_RATE_BURST (default 10). It's nil when ASCII_RATE_LIMIT is 0.
+func limiter() *tokenBucket {
+	sendLimiterMu.Lock()
+	defer sendLimiterMu.Unlock()
+	if !sendLimiterLoaded {
+		sendLimiter, sendLimiterLoaded = nil, true
+		if perMinute := envInt("ASCII_RATE_LIMIT", 60); perMinute > 0 {
+			sendLimiter = newTokenBucket(perMinute, max(1, envInt("ASCII_RATE_BURST", 10)))
+		}
+	}
+	return sendLimiter
+}
+
+// resetLimiter has the next call to limiter make a new bucket from the
+// settings as they are then. Requests already holding the old one keep it.
+func resetLimiter() {
+	sendLimiterMu.Lock()
+	defer sendLimiterMu.Unlock()
+	sendLimiter, sendLimiterLoaded = nil, false
+}
+
+// rateLimitedClient holds requests back to stay under the account's limits
+// during rapid iteration.
+type rateLimitedClient struct {
+	client StreamingClient
+	bucket *tokenBucket
+}
+
+func (c rateLimitedClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if err := c.bucket.wait(ctx); err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	return c.client.CreateChatCompletion(ctx, req)
+}
+
+func (c rateLimitedClient) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionStream, error) {
+	if err := c.bucket.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.client.CreateChatCompletionStream(ctx, req)
+}
+
+// rateLimitDelay returns how long the next request with client would be held
+// back for.
+func rateLimitDelay(client ChatClient) time.Duration {
+	if limited, ok := client.(rateLimitedClient); ok {
+		return limited.bucket.pending()
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// fakeClock is a time that only moves when the test says so.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func (c *fakeClock) time() time.Time { return c.now }
+
+// clockedBucket returns a bucket that tells time by the returned clock.
+func clockedBucket(perMinute int, capacity int) (*tokenBucket, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	b := newTokenBucket(perMinute, capacity)
+	b.last = clock.now
+	b.now = clock.time
+	return b, clock
+}
+
+func TestTokenBucketBurst(t *testing.T) {
+	b, _ := clockedBucket(60, 3)
+	for i, want := range []time.Duration{0, 0, 0, time.Second, 2 * time.Second} {
+		if got := b.reserve(); got != want {
+			t.Errorf("request %d waits %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	b, clock := clockedBucket(60, 2)
+	b.reserve()
+	b.reserve()
+	if got := b.pending(); got != time.Second {
+		t.Errorf("pending() = %s with the bucket empty, want 1s", got)
+	}
+	clock.advance(500 * time.Millisecond)
+	if got := b.pending(); got != 500*time.Millisecond {
+		t.Errorf("pending() = %s half a token later, want 500ms", got)
+	}
+	clock.advance(time.Minute)
+	// Refilling stops at capacity
+	for i, want := range []time.Duration{0, 0, time.Second} {
+		if got := b.reserve(); got != want {
+			t.Errorf("request %d after a quiet spell waits %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestTokenBucketPendingDoesNotTake(t *testing.T) {
+	b, _ := clockedBucket(60, 1)
+	for range 3 {
+		if got := b.pending(); got != 0 {
+			t.Fatalf("pending() = %s, want 0 with a token left", got)
+		}
+	}
+	if got := b.reserve(); got != 0 {
+		t.Errorf("reserve() = %s after checking pending, want the token still there", got)
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	// 600 a minute is one every 100ms
+	b := newTokenBucket(600, 1)
+	start := time.Now()
+	for range 3 {
+		if err := b.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond || elapsed > time.Second {
+		t.Errorf("3 requests took %s, want about 200ms", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	slow := newTokenBucket(1, 1)
+	slow.reserve()
+	if err := slow.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait() = %v once the context was done, want its error", err)
+	}
+}
+
+func TestLimiterSettings(t *testing.T) {
+	tests := []struct {
+		limit, burst string
+		want         bool
+		perSecond    float64
+		capacity     float64
+	}{
+		{limit: "", burst: "", want: true, perSecond: 1, capacity: 10},
+		{limit: "120", burst: "0", want: true, perSecond: 2, capacity: 1},
+		{limit: "0", burst: "", want: false},
+	}
+	for _, tt := range tests {
+		testEnv(t)
+		t.Setenv("ASCII_RATE_LIMIT", tt.limit)
+		t.Setenv("ASCII_RATE_BURST", tt.burst)
+		resetLimiter()
+		b := limiter()
+		if (b != nil) != tt.want {
+			t.Fatalf("limiter() = %v with ASCII_RATE_LIMIT=%q, want a bucket %v", b, tt.limit, tt.want)
+		}
+		if b != nil && (b.rate != tt.perSecond || b.capacity != tt.capacity) {
+			t.Errorf("limiter() rate %v capacity %v, want %v and %v", b.rate, b.capacity, tt.perSecond, tt.capacity)
+		}
+		if b != nil && limiter() != b {
+			t.Error("limiter() made a new bucket, want every request to share one")
+		}
+	}
+}
+
+func TestChatWaitingForRateLimit(t *testing.T) {
+	b, _ := clockedBucket(1, 1)
+	b.reserve()
+	m := newTestChat(t, rateLimitedClient{client: streaming(t), bucket: b})
+	m.textarea.SetValue("a cat")
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(chatModel)
+	if line := ansi.Strip(m.focusLine()); !strings.Contains(line, "waiting to respect rate limit") {
+		t.Errorf("focus line = %q, want the rate limit wait shown", line)
+	}
+}
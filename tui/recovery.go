@@ -0,0 +1,88 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+// autosaveMsg takes a snapshot. It carries the id of the timer it's from, so one
+// left running while away from the chat doesn't double up with the one started
+// on coming back.
+type autosaveMsg struct {
+	id int
+}
+
+// recoveryFile is where the conversation is snapshotted while chatting. It's
+// removed on a clean exit, so finding it on startup means the last session
+// crashed.
+func recoveryFile() string {
+	if path := os.Getenv("ASCII_RECOVERY_FILE"); path != "" {
+		return path
+	}
+	return ".ascii-recovery.md"
+}
+
+// restartAutosave starts a new autosave timer, replacing any that's running.
+func (m *chatModel) restartAutosave() tea.Cmd {
+	m.autosaves++
+	return autosaveTick(m.autosaves)
+}
+
+// autosaveTick schedules the next snapshot, every ASCII_AUTOSAVE_SECONDS.
+// Setting it to 0 turns snapshots off.
+func autosaveTick(id int) tea.Cmd {
+	seconds := envInt("ASCII_AUTOSAVE_SECONDS", 30)
+	if seconds <= 0 {
+		return nil
+	}
+	return tea.Tick(time.Duration(seconds)*time.Second, func(time.Time) tea.Msg {
+		return autosaveMsg{id: id}
+	})
+}
+
+func saveRecovery(history []openai.ChatCompletionMessage) error {
+	return writeFile(recoveryFile(), []byte(transcriptMarkdown(history, nil)), 0o600)
+}
+
+// loadRecovery returns the conversation left behind by a crashed session.
+func loadRecovery() ([]turn, bool) {
+	md, err := os.ReadFile(recoveryFile())
+	if err != nil {
+		return nil, false
+	}
+	turns, err := parseTranscript(string(md))
+	if err != nil {
+		return nil, false
+	}
+	return turns, true
+}
+
+// ClearRecovery removes the snapshot once a chat has exited cleanly.
+func ClearRecovery() {
+	os.Remove(recoveryFile())
+}
+
+// restoreTurns brings back a conversation read from a transcript, from a
+// crashed session or a resumed one.
+func (m *chatModel) restoreTurns(turns []turn) {
+	for _, t := range turns {
+		m.history = append(m.history, openai.ChatCompletionMessage{Role: t.role, Content: t.text})
+		switch t.role {
+		case openai.ChatMessageRoleUser:
+			m.messages = append(m.messages, m.senderStyle.Render(userLabel()+": ")+t.text)
+		case openai.ChatMessageRoleAssistant:
+			m.messages = append(m.messages, m.senderStyle.Render(assistantLabel()+": "+t.text))
+		default:
+			// Shown the way the composer shows it
+			m.messages = append(m.messages, m.senderStyle.Render("("+t.role+"): ")+t.text)
+		}
+	}
+	m.recovered = nil
+	m.refresh()
+}
@@ -0,0 +1,99 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+// chatAfterCrash leaves a recovery file behind as if the last session had
+// crashed mid conversation, then starts a chat like newTestChat.
+func chatAfterCrash(t *testing.T, client ChatClient) chatModel {
+	t.Helper()
+	testEnv(t)
+	err := saveRecovery([]openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "draw a cat"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "here's a cat"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewChatModel().WithClient(client)
+	m.resize(tea.WindowSizeMsg{Width: 80, Height: 40})
+	return m
+}
+
+func TestChatOffersRestore(t *testing.T) {
+	m := chatAfterCrash(t, answering("unused"))
+	if m.recovered == nil || !strings.Contains(m.View(), "didn't exit cleanly") {
+		t.Fatal("a crashed session wasn't offered to restore")
+	}
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	m = next.(chatModel)
+	if m.recovered != nil || len(m.history) != 2 || m.history[1].Content != "here's a cat" {
+		t.Errorf("history after ctrl+r = %v, want the crashed conversation", m.history)
+	}
+}
+
+func TestChatStartOverInsteadOfRestoring(t *testing.T) {
+	m := chatAfterCrash(t, answering("```\ncat\n```"))
+	m.textarea.SetValue("draw a dog")
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(chatModel)
+	if m.recovered != nil {
+		t.Error("sending a message kept the crashed session to restore")
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if m = next.(chatModel); len(m.history) != 1 {
+		t.Errorf("history = %v after ctrl+r, want only the new message", m.history)
+	}
+}
+
+func TestChatWithoutCrashedSession(t *testing.T) {
+	m := newTestChat(t, answering("unused"))
+	if m.recovered != nil || strings.Contains(m.View(), "didn't exit cleanly") {
+		t.Error("restore was offered with no recovery file")
+	}
+}
+
+func TestAutosaveKeepsOneTimer(t *testing.T) {
+	m := newTestChat(t, answering("unused"))
+	t.Setenv("ASCII_AUTOSAVE_SECONDS", "30")
+	m.history = []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "draw a cat"}}
+	// The timer from Init is still running when the chat is come back to
+	stale := autosaveMsg{id: m.autosaves}
+	next, _ := returnToChat(m)
+	m = next.(chatModel)
+
+	next, cmd := m.Update(stale)
+	m = next.(chatModel)
+	if cmd != nil {
+		t.Error("a replaced timer kept going")
+	}
+	if _, err := os.Stat(recoveryFile()); err == nil {
+		t.Error("a replaced timer took a snapshot")
+	}
+
+	next, cmd = m.Update(autosaveMsg{id: m.autosaves})
+	m = next.(chatModel)
+	if cmd == nil {
+		t.Error("the current timer stopped")
+	}
+	if _, err := os.Stat(recoveryFile()); err != nil {
+		t.Errorf("the current timer didn't take a snapshot: %v", err)
+	}
+}
+
+func TestAutosaveSurvivesReset(t *testing.T) {
+	m := newTestChat(t, answering("unused"))
+	m.restartAutosave()
+	if reset := m.reset(); reset.autosaves != m.autosaves {
+		t.Errorf("autosaves = %d after reset, want %d so the running timer carries on", reset.autosaves, m.autosaves)
+	}
+}
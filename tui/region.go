@@ -0,0 +1,79 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sashabaranov/go-openai"
+)
+
+// selectionStyle highlights the cells of art being selected.
+var selectionStyle = lipgloss.NewStyle().Reverse(true)
+
+// regionPrompt asks for just the part of art inside r to be redrawn, at the
+// same size so it can be spliced back in.
+func regionPrompt(art string, r region) string {
+	return fmt.Sprintf(
+		"Here is some ascii art:\n%s\n%s\n%s\n\nRedraw only the part from line %d to %d and column %d to %d, which currently looks like this:\n%s\n%s\n%s\n\nImprove it so it fits in with the rest of the art. Reply with only the redrawn part in a code block, exactly %d characters wide and %d lines tall.",
+		fence, stripFence(art), fence,
+		r.top+1, r.bottom+1, r.left+1, r.right+1,
+		fence, cropRegion(art, r), fence,
+		r.width(), r.height(),
+	)
+}
+
+// regionMsg is the art with a region redrawn, or why it couldn't be, along
+// with the art the region was picked from.
+type regionMsg struct {
+	from string
+	art  string
+	err  error
+}
+
+// regenerateRegion has the chat's model redraw the part of art inside r in
+// the background. The art with the new part spliced in comes back as a
+// regionMsg. The request is made on its own, so it doesn't end up in the
+// conversation.
+func regenerateRegion(chat *chatModel, art string, r region) tea.Cmd {
+	client := chat.aiClient
+	req := newRequest(chat.model, []openai.ChatCompletionMessage{{
+		Role:    openai.ChatMessageRoleUser,
+		Content: regionPrompt(art, r),
+	}}, chat.seed, chat.style)
+	return func() tea.Msg {
+		resp, err := complete(client, req)
+		if err != nil {
+			return regionMsg{from: art, err: err}
+		}
+		patch, ok := extractArt(resp.Text)
+		if !ok {
+			return regionMsg{from: art, err: errors.New("no ascii art found in the response")}
+		}
+		return regionMsg{from: art, art: spliceRegion(art, r, patch)}
+	}
+}
+
+// highlightRegion renders art without its fences, with the cells inside r
+// highlighted and the cursor cell shown even when r is a single cell.
+func highlightRegion(art string, r region) string {
+	cells := artCells(art, r.right+1)
+	lines := make([]string, 0, len(cells))
+	for row, line := range cells {
+		var b strings.Builder
+		for col, cell := range line {
+			if r.contains(row, col) {
+				b.WriteString(selectionStyle.Render(string(cell)))
+			} else {
+				b.WriteRune(cell)
+			}
+		}
+		lines = append(lines, b.String())
+	}
+	return strings.Join(lines, "\n")
+}
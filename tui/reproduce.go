@@ -0,0 +1,57 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"errors"
+
+	db "github.com/ericulley/ascii/data"
+	"github.com/sashabaranov/go-openai"
+)
+
+// artRecord is art from the chat ready to save, along with everything it was
+// generated with so it can be reproduced later.
+func (m chatModel) artRecord(art string, seed *int) db.AsciiRecord {
+	return db.AsciiRecord{
+		Art:         art,
+		Seed:        seed,
+		Style:       m.style,
+		Model:       m.model,
+		Prompt:      lastPrompt(m.history),
+		Temperature: temperature(),
+		TopP:        topP(),
+		MaxTokens:   maxTokens(),
+	}
+}
+
+// Reproduce generates saved art again from the prompt and settings it was
+// saved with. Settings that weren't saved, like those of art saved before
+// they were, fall back to the current ones.
+func Reproduce(client ChatClient, record db.AsciiRecord) (Generation, error) {
+	if record.Prompt == "" {
+		return Generation{}, errors.New(record.Name + " was saved without the prompt it was made from")
+	}
+	model := record.Model
+	if model == "" {
+		model = models()[0]
+	}
+	style := record.Style
+	if style == "" {
+		style = envStyle()
+	}
+	req := newRequest(model, []openai.ChatCompletionMessage{{
+		Role:    openai.ChatMessageRoleUser,
+		Content: record.Prompt,
+	}}, record.Seed, style)
+	if record.MaxTokens > 0 {
+		req.MaxTokens = record.MaxTokens
+	}
+	if record.Temperature != nil {
+		req.Temperature = float32(*record.Temperature)
+	}
+	if record.TopP != nil {
+		req.TopP = float32(*record.TopP)
+	}
+	return generate(client, record.Prompt, req)
+}
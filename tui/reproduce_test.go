@@ -0,0 +1,88 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"reflect"
+	"testing"
+
+	db "github.com/ericulley/ascii/data"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestReproduceRoundTrip(t *testing.T) {
+	testDB(t)
+	t.Setenv("OPENAI_SEED", "42")
+	t.Setenv("OPENAI_MAX_TOKENS", "300")
+	t.Setenv("ASCII_TEMPERATURE", "0.25")
+	t.Setenv("ASCII_TOP_P", "0.5")
+	client := answering("```\n=^.^=\n```")
+	m := newTestChat(t, client)
+	m = sendThrough(t, m, "a cat")
+	if m.ascii == nil {
+		t.Fatal("no art in the response")
+	}
+
+	record := m.artRecord(m.ascii.art, m.ascii.seed)
+	record.Name = "cat.txt"
+	if err := db.SaveArtToDB(record); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := db.ArtByNameOrId("cat.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Prompt != "a cat" || saved.Model != m.model || saved.MaxTokens != 300 ||
+		saved.Seed == nil || *saved.Seed != 42 ||
+		saved.Temperature == nil || *saved.Temperature != 0.25 ||
+		saved.TopP == nil || *saved.TopP != 0.5 {
+		t.Fatalf("saved %+v, want everything the art was generated with", saved)
+	}
+
+	// The settings have moved on since, the saved ones are used all the same
+	for _, key := range []string{"OPENAI_SEED", "OPENAI_MAX_TOKENS", "ASCII_TEMPERATURE", "ASCII_TOP_P"} {
+		t.Setenv(key, "")
+	}
+	gen, err := Reproduce(client, saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen.Art != "=^.^=" || gen.Prompt != "a cat" {
+		t.Errorf("reproduced %+v, want the art again", gen)
+	}
+	sent := client.sent()
+	original, again := sent[0], sent[len(sent)-1]
+	if again.Model != original.Model || again.MaxTokens != original.MaxTokens ||
+		again.Temperature != original.Temperature || again.TopP != original.TopP ||
+		!reflect.DeepEqual(again.Seed, original.Seed) {
+		t.Errorf("reproduced with %+v, want the settings of %+v", again, original)
+	}
+	if prompt := again.Messages[len(again.Messages)-1]; prompt.Role != openai.ChatMessageRoleUser || prompt.Content != "a cat" {
+		t.Errorf("reproduced with %+v, want the saved prompt", prompt)
+	}
+}
+
+func TestReproduceMissingSettings(t *testing.T) {
+	testEnv(t)
+	t.Setenv("OPENAI_SEED", "")
+	t.Setenv("OPENAI_MAX_TOKENS", "150")
+	t.Setenv("ASCII_TEMPERATURE", "0.7")
+	t.Setenv("ASCII_TOP_P", "")
+	client := answering("```\n(o.o)\n```")
+	if _, err := Reproduce(client, db.AsciiRecord{Name: "old.txt", Prompt: "a face"}); err != nil {
+		t.Fatal(err)
+	}
+	req := client.sent()[0]
+	if req.Model != models()[0] || req.MaxTokens != 150 || req.Temperature != 0.7 || req.TopP != 0 || req.Seed != nil {
+		t.Errorf("sent %+v, want the current settings for those that weren't saved", req)
+	}
+
+	_, err := Reproduce(client, db.AsciiRecord{Name: "older.txt", Art: "(o.o)"})
+	if err == nil || err.Error() != "older.txt was saved without the prompt it was made from" {
+		t.Errorf("err = %v, want art without a prompt turned away", err)
+	}
+	if len(client.sent()) != 1 {
+		t.Error("sent a request for art without a prompt")
+	}
+}
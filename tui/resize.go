@@ -0,0 +1,32 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// resizeMsg applies a terminal size once resizing has settled. It carries the
+// id of the resize it's for, so only the last of a burst is laid out.
+type resizeMsg struct {
+	id   int
+	size tea.WindowSizeMsg
+}
+
+// resizeDebounce is how long the terminal has to stay the same size before
+// the chat is laid out again, ASCII_RESIZE_DEBOUNCE_MS (default 50). Dragging
+// a window's edge sends a stream of sizes, and laying out for each one
+// flickers. 0 lays out every size as it comes.
+func resizeDebounce() time.Duration {
+	return time.Duration(max(0, envInt("ASCII_RESIZE_DEBOUNCE_MS", 50))) * time.Millisecond
+}
+
+// debounceResize waits out resizeDebounce before laying out for size.
+func debounceResize(id int, size tea.WindowSizeMsg) tea.Cmd {
+	return tea.Tick(resizeDebounce(), func(time.Time) tea.Msg {
+		return resizeMsg{id: id, size: size}
+	})
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestResizeDebounce(t *testing.T) {
+	for value, want := range map[string]time.Duration{"": 50 * time.Millisecond, "0": 0, "-5": 0, "200": 200 * time.Millisecond} {
+		t.Setenv("ASCII_RESIZE_DEBOUNCE_MS", value)
+		if got := resizeDebounce(); got != want {
+			t.Errorf("resizeDebounce() = %s with ASCII_RESIZE_DEBOUNCE_MS=%q, want %s", got, value, want)
+		}
+	}
+}
+
+func TestChatResizeBurst(t *testing.T) {
+	m := newTestChat(t, answering("unused"))
+	t.Setenv("ASCII_RESIZE_DEBOUNCE_MS", "20")
+	widthAt80 := m.viewport.Width
+
+	cmds := []tea.Cmd{}
+	for width := 81; width <= 100; width++ {
+		next, cmd := m.Update(tea.WindowSizeMsg{Width: width, Height: 30})
+		m = next.(chatModel)
+		if cmd == nil {
+			t.Fatalf("resizing to %d was laid out right away, want it debounced", width)
+		}
+		cmds = append(cmds, cmd)
+	}
+	if m.viewport.Width != widthAt80 || m.width != 80 {
+		t.Fatalf("laid out at %d during the burst, want the layout to wait", m.width)
+	}
+
+	start := time.Now()
+	msgs := []resizeMsg{}
+	for _, cmd := range cmds {
+		msgs = append(msgs, cmd().(resizeMsg))
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("resizes arrived after %s, want them held for the debounce", elapsed)
+	}
+	// The last size arrives before some of the stale ones
+	for _, msg := range append(msgs[len(msgs)-1:], msgs[:len(msgs)-1]...) {
+		next, _ := m.Update(msg)
+		m = next.(chatModel)
+	}
+	if m.width != 100 || m.height != 30 || m.viewport.Width != widthAt80+20 {
+		t.Errorf("laid out at %dx%d with a viewport %d wide, want the last size, 100x30", m.width, m.height, m.viewport.Width)
+	}
+}
+
+func TestChatFirstSizeNotDebounced(t *testing.T) {
+	testEnv(t)
+	t.Setenv("ASCII_RESIZE_DEBOUNCE_MS", "50")
+	next, cmd := NewChatModel().Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	if m := next.(chatModel); cmd != nil || m.width != 80 {
+		t.Errorf("the first size waited, want the chat laid out straight away")
+	}
+}
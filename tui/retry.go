@@ -0,0 +1,101 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// retryable reports whether a failed request never produced a completion, so
+// sending it again can't get the same prompt billed twice. Only errors where
+// openai never saw the request, or turned it away with a rate limit, qualify.
+// Anything else, such as a timeout while waiting on the response, might have
+// completed on openai's side and is returned to the user instead.
+func retryable(err error) bool {
+	if tooManyRequests(err) {
+		return true
+	}
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	if errors.As(err, &apiErr) || errors.As(err, &reqErr) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		// The connection was never made
+		return opErr.Op == "dial"
+	}
+	return false
+}
+
+// tooManyRequests reports whether openai turned a request away with a rate
+// limit.
+func tooManyRequests(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// contextTooLong reports whether openai turned a request away because the
+// conversation no longer fits in the model's context window. Other bad
+// requests are reported the same way, so this goes by the error code.
+func contextTooLong(err error) bool {
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusBadRequest {
+		return false
+	}
+	code, _ := apiErr.Code.(string)
+	return code == "context_length_exceeded"
+}
+
+// trimHistory drops the oldest half of the conversation to make room in the
+// context window. The last message, the one being sent, is always kept.
+func trimHistory(history []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	if len(history) <= 1 {
+		return history
+	}
+	drop := max(1, (len(history)-1)/2)
+	return append([]openai.ChatCompletionMessage{}, history[drop:]...)
+}
+
+// shiftPositions moves the dividers, the selection and the last prompt at or
+// past from in the history by by, as messages are added or dropped before
+// them, but never before the start.
+func (m *chatModel) shiftPositions(from, by int) {
+	shift := func(position int) int {
+		if position < from {
+			return position
+		}
+		return max(0, position+by)
+	}
+	for i, divider := range m.dividers {
+		m.dividers[i] = shift(divider)
+	}
+	m.selected = shift(m.selected)
+	m.lastPromptAt = shift(m.lastPromptAt)
+}
+
+// retries returns how many times a retryable request is sent again, RETRY_MAX
+// (default 2), or none when RETRY_ENABLED is false so the first error is
+// reported straight away.
+func retries() int {
+	if !envBool("RETRY_ENABLED", true) {
+		return 0
+	}
+	return max(0, envInt("RETRY_MAX", 2))
+}
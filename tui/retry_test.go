@@ -0,0 +1,211 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+// contextLengthErr is how openai turns away a conversation too long for the
+// model.
+var contextLengthErr = &openai.APIError{
+	Code:           "context_length_exceeded",
+	Message:        "This model's maximum context length is 128000 tokens.",
+	HTTPStatusCode: http.StatusBadRequest,
+}
+
+func TestContextTooLong(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "context length", err: contextLengthErr, want: true},
+		{name: "wrapped", err: errors.Join(errors.New("sending"), contextLengthErr), want: true},
+		{name: "other bad request", err: &openai.APIError{Code: "invalid_request_error", HTTPStatusCode: http.StatusBadRequest}},
+		{name: "not a bad request", err: &openai.APIError{Code: "context_length_exceeded", HTTPStatusCode: http.StatusInternalServerError}},
+		{name: "no code", err: &openai.APIError{HTTPStatusCode: http.StatusBadRequest}},
+		{name: "not from the api", err: errors.New("context_length_exceeded")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contextTooLong(tt.err); got != tt.want {
+				t.Errorf("contextTooLong() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimHistory(t *testing.T) {
+	history := []openai.ChatCompletionMessage{}
+	for _, content := range []string{"1", "2", "3", "4", "5"} {
+		history = append(history, openai.ChatCompletionMessage{Content: content})
+	}
+	got := trimHistory(history)
+	if len(got) != 3 || got[0].Content != "3" || got[2].Content != "5" {
+		t.Errorf("trimHistory() = %+v, want the newest 3", got)
+	}
+	if got := trimHistory(history[:2]); len(got) != 1 || got[0].Content != "2" {
+		t.Errorf("trimHistory() = %+v, want only the message being sent", got)
+	}
+	if got := trimHistory(history[:1]); len(got) != 1 {
+		t.Errorf("trimHistory() = %+v, want the message being sent kept", got)
+	}
+}
+
+// sendThrough has the chat send prompt, answering any retries, and returns the
+// chat once a response is shown.
+func sendThrough(t *testing.T, m chatModel, prompt string) chatModel {
+	t.Helper()
+	m.textarea.SetValue(prompt)
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	for next.(chatModel).waiting {
+		next, cmd = next.Update(awaitMsg[responseMsg](t, cmd))
+	}
+	return next.(chatModel)
+}
+
+func TestChatContextTooLong(t *testing.T) {
+	client := answering("ok")
+	m := newTestChat(t, client)
+	for _, prompt := range []string{"one", "two"} {
+		m = sendThrough(t, m, prompt)
+	}
+	client.failingFirst(contextLengthErr)
+	m = sendThrough(t, m, "three")
+
+	sent := client.sent()
+	if len(sent) != 4 {
+		t.Fatalf("sent %d requests, want the long one sent again once", len(sent))
+	}
+	if retry := sent[3].Messages; len(retry) >= len(sent[2].Messages) || retry[len(retry)-1].Content != "three" {
+		t.Errorf("retried with %+v, want a shorter history ending with the prompt", retry)
+	}
+	if view := strings.Join(m.messages, "\n"); !strings.Contains(view, "oldest messages were dropped") || strings.Contains(view, "Completion error") {
+		t.Errorf("messages = %q, want the trim explained and no error", m.messages)
+	}
+}
+
+func TestChatContextTooLongKeepsPositions(t *testing.T) {
+	client := answering("ok")
+	m := newTestChat(t, client)
+	for _, prompt := range []string{"one", "two"} {
+		m = sendThrough(t, m, prompt)
+	}
+	two := len(m.history) - 2
+	m.dividers = []int{0, two}
+	m.selected = two
+	client.failingFirst(contextLengthErr)
+	m = sendThrough(t, m, "three")
+
+	if m.history[m.selected].Content != "two" {
+		t.Errorf("selected %+v, want the same message selected after the trim", m.history[m.selected])
+	}
+	if len(m.dividers) != 2 || m.dividers[0] != 0 || m.history[m.dividers[1]].Content != "two" {
+		t.Errorf("dividers = %v in %+v, want the first at the start and the second still before two", m.dividers, m.history)
+	}
+	if m.history[m.lastPromptAt].Content != "three" {
+		t.Errorf("last prompt at %d in %+v, want three's position", m.lastPromptAt, m.history)
+	}
+}
+
+func TestChatContextTooLongOnce(t *testing.T) {
+	client := answering("ok")
+	m := newTestChat(t, client)
+	m = sendThrough(t, m, "one")
+	client.failingFirst(contextLengthErr, contextLengthErr)
+	m = sendThrough(t, m, "two")
+	if n := len(client.sent()); n != 3 {
+		t.Errorf("sent %d requests, want one retry only", n)
+	}
+	if last := m.messages[len(m.messages)-1]; !strings.Contains(last, "Completion error") {
+		t.Errorf("last message = %q, want the error shown after the retry", last)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "rate limited", err: &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "rate limited request", err: &openai.RequestError{HTTPStatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "dns", err: &net.DNSError{Err: "no such host"}, want: true},
+		{name: "dial", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "read", err: &net.OpError{Op: "read", Err: errors.New("connection reset")}},
+		{name: "bad request", err: &openai.APIError{HTTPStatusCode: http.StatusBadRequest}},
+		{name: "server error", err: &openai.RequestError{HTTPStatusCode: http.StatusInternalServerError}},
+		{name: "other", err: errors.New("timeout")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		enabled, max string
+		want         int
+	}{
+		{enabled: "", max: "", want: 2},
+		{enabled: "true", max: "5", want: 5},
+		{enabled: "false", max: "5", want: 0},
+		{enabled: "", max: "-1", want: 0},
+	}
+	for _, tt := range tests {
+		t.Setenv("RETRY_ENABLED", tt.enabled)
+		t.Setenv("RETRY_MAX", tt.max)
+		if got := retries(); got != tt.want {
+			t.Errorf("retries() = %d with RETRY_ENABLED=%q RETRY_MAX=%q, want %d", got, tt.enabled, tt.max, tt.want)
+		}
+	}
+}
+
+func TestCompleteRetries(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	tests := []struct {
+		name     string
+		enabled  string
+		requests int
+		wantErr  bool
+	}{
+		{name: "enabled", enabled: "true", requests: 2, wantErr: false},
+		{name: "disabled", enabled: "false", requests: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testEnv(t)
+			t.Setenv("RETRY_ENABLED", tt.enabled)
+			t.Setenv("RETRY_MAX", "1")
+			client := answering("ok").failingFirst(dialErr)
+			_, err := complete(client, openai.ChatCompletionRequest{Model: "gpt-4o"})
+			if (err != nil) != tt.wantErr || len(client.sent()) != tt.requests {
+				t.Errorf("error = %v after %d requests, want %d requests", err, len(client.sent()), tt.requests)
+			}
+			if tt.wantErr && !errors.Is(err, dialErr) {
+				t.Errorf("error = %v, want the first error as it was", err)
+			}
+		})
+	}
+}
+
+func TestCompleteDoesNotRetryOtherErrors(t *testing.T) {
+	testEnv(t)
+	client := answering("ok").failingFirst(&openai.APIError{HTTPStatusCode: http.StatusInternalServerError})
+	if _, err := complete(client, openai.ChatCompletionRequest{Model: "gpt-4o"}); err == nil || len(client.sent()) != 1 {
+		t.Errorf("error = %v after %d requests, want a server error returned without retrying", err, len(client.sent()))
+	}
+}
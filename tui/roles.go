@@ -0,0 +1,46 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"slices"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// composerRoles are the roles alt+a cycles a message through when
+// ASCII_ROLE_COMPOSER is on. Only user messages are sent right away.
+var composerRoles = []string{
+	openai.ChatMessageRoleUser,
+	openai.ChatMessageRoleSystem,
+	openai.ChatMessageRoleAssistant,
+}
+
+// nextRole returns the role after role in composerRoles, wrapping around.
+func nextRole(role string) string {
+	return composerRoles[(slices.Index(composerRoles, role)+1)%len(composerRoles)]
+}
+
+// insertMessage adds a message with an explicit role to the history. System
+// messages go after any others at the start, where instructions are expected,
+// and anything else goes at the end.
+func insertMessage(history []openai.ChatCompletionMessage, role string, content string) []openai.ChatCompletionMessage {
+	message := openai.ChatCompletionMessage{Role: role, Content: content}
+	if role != openai.ChatMessageRoleSystem {
+		return append(history, message)
+	}
+	return slices.Insert(slices.Clone(history), insertedAt(history, role), message)
+}
+
+// insertedAt is where insertMessage puts a message with role in the history.
+func insertedAt(history []openai.ChatCompletionMessage, role string) int {
+	if role != openai.ChatMessageRoleSystem {
+		return len(history)
+	}
+	at := 0
+	for at < len(history) && history[at].Role == openai.ChatMessageRoleSystem {
+		at++
+	}
+	return at
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestInsertMessage(t *testing.T) {
+	system := func(content string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: content}
+	}
+	user := func(content string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: content}
+	}
+	tests := []struct {
+		name    string
+		history []openai.ChatCompletionMessage
+		role    string
+		want    []string
+	}{
+		{name: "system into empty", history: nil, role: openai.ChatMessageRoleSystem, want: []string{"new"}},
+		{name: "system before the conversation", history: []openai.ChatCompletionMessage{user("a"), user("b")}, role: openai.ChatMessageRoleSystem, want: []string{"new", "a", "b"}},
+		{name: "system after other system messages", history: []openai.ChatCompletionMessage{system("s"), user("a")}, role: openai.ChatMessageRoleSystem, want: []string{"s", "new", "a"}},
+		{name: "assistant at the end", history: []openai.ChatCompletionMessage{system("s"), user("a")}, role: openai.ChatMessageRoleAssistant, want: []string{"s", "a", "new"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := append([]openai.ChatCompletionMessage{}, tt.history...)
+			got := insertMessage(tt.history, tt.role, "new")
+			contents := []string{}
+			for _, message := range got {
+				contents = append(contents, message.Content)
+			}
+			if !slices.Equal(contents, tt.want) {
+				t.Errorf("insertMessage() = %q, want %q", contents, tt.want)
+			}
+			if !reflect.DeepEqual(tt.history, original) && len(original) > 0 {
+				t.Errorf("history changed to %+v", tt.history)
+			}
+		})
+	}
+}
+
+func TestChatRoleComposer(t *testing.T) {
+	altA := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a"), Alt: true}
+	client := answering("ok")
+	m := newTestChat(t, client)
+	t.Setenv("ASCII_ROLE_COMPOSER", "")
+	next, _ := m.Update(altA)
+	if m = next.(chatModel); m.role != openai.ChatMessageRoleUser {
+		t.Fatalf("role = %q with the composer off, want user", m.role)
+	}
+
+	t.Setenv("ASCII_ROLE_COMPOSER", "true")
+	next, _ = m.Update(altA)
+	m = next.(chatModel)
+	if m.role != openai.ChatMessageRoleSystem {
+		t.Fatalf("role = %q after alt+a, want system", m.role)
+	}
+	m.textarea.SetValue("Only draw cats.")
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(chatModel)
+	if cmd != nil || m.waiting || len(client.sent()) != 0 {
+		t.Error("a system message was sent")
+	}
+	if m.role != openai.ChatMessageRoleUser {
+		t.Errorf("role = %q after adding the message, want user again", m.role)
+	}
+
+	m = sendThrough(t, m, "draw a dog")
+	sent := client.sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(sent))
+	}
+	found := false
+	for _, message := range sent[0].Messages {
+		found = found || message.Role == openai.ChatMessageRoleSystem && message.Content == "Only draw cats."
+	}
+	if !found {
+		t.Errorf("sent %+v, want the composed system message in it", sent[0].Messages)
+	}
+}
+
+func TestChatRoleComposerKeepsPositions(t *testing.T) {
+	t.Setenv("ASCII_ROLE_COMPOSER", "true")
+	m := newTestChat(t, answering("ok"))
+	m = sendThrough(t, m, "a cat")
+	cat := m.lastPromptAt
+	m.dividers = []int{cat, len(m.history)}
+	m.selected = cat
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a"), Alt: true})
+	m = next.(chatModel)
+	m.textarea.SetValue("Only draw cats.")
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(chatModel)
+
+	if m.history[cat].Role != openai.ChatMessageRoleSystem {
+		t.Fatalf("history = %+v, want the system message where the prompt was", m.history)
+	}
+	if m.history[m.selected].Content != "a cat" || m.history[m.lastPromptAt].Content != "a cat" {
+		t.Errorf("selected %d and last prompt at %d in %+v, want both on the prompt still", m.selected, m.lastPromptAt, m.history)
+	}
+	if want := []int{cat + 1, len(m.history)}; !slices.Equal(m.dividers, want) {
+		t.Errorf("dividers = %v, want %v", m.dividers, want)
+	}
+}
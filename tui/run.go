@@ -0,0 +1,136 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"sync"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Run starts a program for model and blocks until it exits, then saves what's
+// left to save. If Update, View or a command panics, bubbletea restores the
+// terminal and the panic is returned as an error, with the stack written to
+// the ASCII_DEBUG_LOG file when one is set. With ASCII_IDLE_MINUTES set, it quits after that long
+// without input. SIGTERM and SIGHUP quit it the same way as pressing esc.
+func Run(model tea.Model, opts ...tea.ProgramOption) error {
+	debugLog := os.Getenv("ASCII_DEBUG_LOG")
+	if debugLog != "" {
+		f, err := tea.LogToFile(debugLog, "ascii")
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+
+	crashed := &crash{}
+	var idle *idleTimer
+	var p *tea.Program
+	if timeout := idleTimeout(); timeout > 0 {
+		idle = newIdleTimer(timeout, func(msg tea.Msg) { p.Send(msg) })
+		defer idle.stop()
+		opts = append(opts, tea.WithFilter(idle.filter))
+	}
+	p = tea.NewProgram(crashReporter{model: model, crash: crashed}, opts...)
+	// The program quits on SIGINT and SIGTERM by itself. Closing the terminal
+	// or tmux pane it's in sends SIGHUP instead, which would end it without
+	// anything being saved
+	hangup, done := make(chan os.Signal, 1), make(chan struct{})
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer func() {
+		signal.Stop(hangup)
+		close(done)
+	}()
+	go func() {
+		select {
+		case <-hangup:
+			p.Quit()
+		case <-done:
+		}
+	}()
+	final, err := p.Run()
+	if reporter, ok := final.(crashReporter); ok {
+		final = reporter.model
+	}
+	finish(final)
+	if r, ok := crashed.recovered(); ok {
+		return fmt.Errorf("panic: %v", r)
+	}
+	return err
+}
+
+// crash is the first panic in a program, kept so Run can report it once
+// bubbletea has recovered from it.
+type crash struct {
+	mu    sync.Mutex
+	value any
+	seen  bool
+}
+
+// report notes a panic and logs its stack, then panics again for bubbletea
+// to restore the terminal. It's deferred around everything that runs the
+// model's code.
+func (c *crash) report() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	c.mu.Lock()
+	if !c.seen {
+		c.value, c.seen = r, true
+		debugf("panic: %v\n%s", r, debug.Stack())
+	}
+	c.mu.Unlock()
+	panic(r)
+}
+
+func (c *crash) recovered() (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value, c.seen
+}
+
+// wrap reports panics in cmd, and in the commands of a batch it returns.
+func (c *crash) wrap(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer c.report()
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for i := range batch {
+				batch[i] = c.wrap(batch[i])
+			}
+		}
+		return msg
+	}
+}
+
+// crashReporter runs model, reporting any panic in it to crash.
+type crashReporter struct {
+	model tea.Model
+	crash *crash
+}
+
+func (r crashReporter) Init() tea.Cmd {
+	defer r.crash.report()
+	return r.crash.wrap(r.model.Init())
+}
+
+func (r crashReporter) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	defer r.crash.report()
+	model, cmd := r.model.Update(msg)
+	return crashReporter{model: model, crash: r.crash}, r.crash.wrap(cmd)
+}
+
+func (r crashReporter) View() string {
+	defer r.crash.report()
+	return r.model.View()
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+type panicMsg struct{}
+
+// panicky panics on the message its first command sends, or in the command
+// itself.
+type panicky struct{ inCmd bool }
+
+func (m panicky) Init() tea.Cmd {
+	return func() tea.Msg {
+		if m.inCmd {
+			panic("boom in a command")
+		}
+		return panicMsg{}
+	}
+}
+
+func (m panicky) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(panicMsg); ok {
+		panic("boom")
+	}
+	return m, nil
+}
+
+func (m panicky) View() string { return "" }
+
+func TestRunReportsPanic(t *testing.T) {
+	tests := []struct {
+		name  string
+		model panicky
+		want  string
+	}{
+		{name: "update", model: panicky{}, want: "panic: boom"},
+		{name: "command", model: panicky{inCmd: true}, want: "panic: boom in a command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testEnv(t)
+			logFile := filepath.Join(dir, "debug.log")
+			t.Setenv("ASCII_DEBUG_LOG", logFile)
+			// bubbletea prints the panic it recovered from and its stack
+			null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer null.Close()
+			stdout, stderr := os.Stdout, os.Stderr
+			os.Stdout, os.Stderr = null, null
+			defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+			err = Run(tt.model, tea.WithInput(&bytes.Buffer{}), tea.WithOutput(io.Discard))
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("Run() error = %v, want %q", err, tt.want)
+			}
+			log, _ := os.ReadFile(logFile)
+			if !strings.Contains(string(log), tt.want) || !strings.Contains(string(log), "run_test.go") {
+				t.Errorf("debug log doesn't have the panic and its stack:\n%s", log)
+			}
+		})
+	}
+}
+
+func TestRunQuitsOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals can't be sent to the process on windows")
+	}
+	for _, sig := range []syscall.Signal{syscall.SIGTERM, syscall.SIGHUP} {
+		t.Run(sig.String(), func(t *testing.T) {
+			m := newTestChat(t, answering("unused"))
+			m.history = []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleUser, Content: "a cat"},
+				{Role: openai.ChatMessageRoleAssistant, Content: "```\n=^.^=\n```"},
+			}
+			// Caught here as well so a signal sent before Run is listening
+			// doesn't end the tests
+			caught := make(chan os.Signal, 8)
+			signal.Notify(caught, sig)
+			defer signal.Stop(caught)
+
+			// Input that never ends, so only the signal quits the program
+			input, w := io.Pipe()
+			defer w.Close()
+			done := make(chan error, 1)
+			go func() {
+				done <- Run(m, tea.WithInput(input), tea.WithOutput(io.Discard))
+			}()
+			self, err := os.FindProcess(os.Getpid())
+			if err != nil {
+				t.Fatal(err)
+			}
+			timeout := time.After(5 * time.Second)
+		wait:
+			for {
+				if err := self.Signal(sig); err != nil {
+					t.Fatal(err)
+				}
+				select {
+				case err := <-done:
+					if err != nil {
+						t.Fatalf("Run() error = %v, want a clean quit", err)
+					}
+					break wait
+				case <-time.After(50 * time.Millisecond):
+				case <-timeout:
+					t.Fatalf("still running after %s", sig)
+				}
+			}
+
+			data, err := os.ReadFile(conversationFile(m.conversation))
+			if err != nil {
+				t.Fatalf("conversation wasn't saved on the way out: %v", err)
+			}
+			if !strings.Contains(string(data), "=^.^=") {
+				t.Errorf("saved %q, want the whole conversation", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/sashabaranov/go-openai"
+)
+
+// said returns the positions in the history of the messages that were said
+// in the conversation, leaving out instructions like the style.
+func said(history []openai.ChatCompletionMessage) []int {
+	positions := []int{}
+	for i, message := range history {
+		if message.Role == openai.ChatMessageRoleUser || message.Role == openai.ChatMessageRoleAssistant {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// selectMessage moves the selection by step through the messages said, from
+// the latest one when nothing is selected yet.
+func (m *chatModel) selectMessage(step int) {
+	positions := said(m.history)
+	if len(positions) == 0 {
+		return
+	}
+	at := len(positions)
+	for i, position := range positions {
+		if position == m.selected {
+			at = i
+		}
+	}
+	at = min(max(0, at+step), len(positions)-1)
+	m.selected = positions[at]
+}
+
+// messageCopyText is a message the way it's copied. The art is copied
+// without its fences when there is some, the whole message otherwise.
+func messageCopyText(content string) string {
+	if art, ok := extractArt(content); ok {
+		return copyText(art, false)
+	}
+	return content
+}
+
+// copySelected copies the selected message and says how it went.
+func (m *chatModel) copySelected() {
+	if !slices.Contains(said(m.history), m.selected) {
+		return
+	}
+	if err := clipboard.WriteAll(messageCopyText(m.history[m.selected].Content)); err != nil {
+		m.status = fmt.Sprintf("Couldn't copy the message: %v", err)
+	} else {
+		m.status = "Copied the message to the clipboard"
+	}
+}
+
+// selectionLine describes the selected message for under the message box.
+func (m chatModel) selectionLine() string {
+	positions := said(m.history)
+	for i, position := range positions {
+		if position != m.selected {
+			continue
+		}
+		message := m.history[position]
+		label := assistantLabel()
+		if message.Role == openai.ChatMessageRoleUser {
+			label = userLabel()
+		}
+		preview := ansi.Truncate(strings.Join(strings.Fields(message.Content), " "), 40, "…")
+		return fmt.Sprintf("message %d/%d, %s: %s • [/] to pick • y to copy", i+1, len(positions), label, preview)
+	}
+	return ""
+}
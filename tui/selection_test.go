@@ -0,0 +1,110 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestSaid(t *testing.T) {
+	history := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "draw in a box"},
+		{Role: openai.ChatMessageRoleUser, Content: "a cat"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "=^.^="},
+		{Role: openai.ChatMessageRoleSystem, Content: "use only dots"},
+		{Role: openai.ChatMessageRoleUser, Content: "a dog"},
+	}
+	got := said(history)
+	want := []int{1, 2, 4}
+	if len(got) != len(want) {
+		t.Fatalf("said() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("said() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestMessageCopyText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "art loses its fences", content: "Here you go:\n```\n/\\_/\\\n( o.o )\n```\nEnjoy!", want: "/\\_/\\\n( o.o )"},
+		{name: "plain message copied whole", content: "What kind of cat?", want: "What kind of cat?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messageCopyText(tt.content); got != tt.want {
+				t.Errorf("messageCopyText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectAndCopyMessage(t *testing.T) {
+	m := newTestChat(t, answering("unused"))
+	m.history = []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "a cat"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "```\n=^.^=\n```"},
+		{Role: openai.ChatMessageRoleSystem, Content: "use only dots"},
+		{Role: openai.ChatMessageRoleUser, Content: "a dog"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "```\n.o.\n```"},
+	}
+	press := func(keys ...string) {
+		for _, k := range keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+			if k == "tab" {
+				msg = tea.KeyMsg{Type: tea.KeyTab}
+			}
+			next, _ := m.Update(msg)
+			m = next.(chatModel)
+		}
+	}
+
+	press("tab", "[")
+	if m.selected != 4 {
+		t.Fatalf("selected = %d after [, want the latest message", m.selected)
+	}
+	// Going back skips the style instruction, and stops at the first message
+	press("[")
+	if m.selected != 3 {
+		t.Fatalf("selected = %d, want the message before the latest", m.selected)
+	}
+	press("[", "[", "[", "[")
+	if m.selected != 0 {
+		t.Fatalf("selected = %d, want the selection to stop at the first message", m.selected)
+	}
+	press("]")
+	if m.selected != 1 {
+		t.Fatalf("selected = %d after ], want the cat art", m.selected)
+	}
+	if line := m.selectionLine(); !strings.HasPrefix(line, "message 2/4") || !strings.Contains(line, "=^.^=") {
+		t.Errorf("selection line = %q, want it to describe the cat art", line)
+	}
+
+	press("y")
+	switch {
+	case strings.HasPrefix(m.status, "Couldn't copy the message"):
+		// No clipboard to copy to where the tests run
+	case m.status != "Copied the message to the clipboard":
+		t.Errorf("status = %q, want a confirmation", m.status)
+	default:
+		if got, err := clipboard.ReadAll(); err != nil || got != "=^.^=" {
+			t.Errorf("clipboard = %q, %v, want the cat art without its fences", got, err)
+		}
+	}
+
+	press("tab")
+	if m.selected != -1 || m.selectionLine() != "" {
+		t.Errorf("selected = %d, want leaving the conversation to clear the selection", m.selected)
+	}
+}
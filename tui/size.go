@@ -0,0 +1,22 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import "fmt"
+
+// The smallest terminal the chat can be laid out in without breaking.
+const (
+	minWidth  = 30
+	minHeight = 10
+)
+
+// tooSmall returns a message asking for a bigger terminal when width or
+// height is below the minimum. A zero size hasn't been reported yet and
+// isn't too small.
+func tooSmall(width, height int) (string, bool) {
+	if width == 0 || width >= minWidth && height >= minHeight {
+		return "", false
+	}
+	return fmt.Sprintf("Terminal too small (need at least %dx%d, have %dx%d). Resize it to continue.", minWidth, minHeight, width, height), true
+}
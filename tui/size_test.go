@@ -0,0 +1,46 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTooSmall(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          bool
+	}{
+		{width: 0, height: 0, want: false},
+		{width: minWidth, height: minHeight, want: false},
+		{width: 120, height: 40, want: false},
+		{width: minWidth - 1, height: minHeight, want: true},
+		{width: minWidth, height: minHeight - 1, want: true},
+		{width: 1, height: 1, want: true},
+	}
+	for _, tt := range tests {
+		msg, got := tooSmall(tt.width, tt.height)
+		if got != tt.want {
+			t.Errorf("tooSmall(%d, %d) = %v, want %v", tt.width, tt.height, got, tt.want)
+		}
+		if got && !strings.Contains(msg, "need at least 30x10") {
+			t.Errorf("tooSmall(%d, %d) message = %q, want the minimum size", tt.width, tt.height, msg)
+		}
+	}
+}
+
+func TestChatViewTooSmall(t *testing.T) {
+	m := newTestChat(t, answering("unused"))
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 20, Height: 5})
+	if view := next.View(); !strings.HasPrefix(view, "Terminal too small") {
+		t.Errorf("View() at 20x5 = %q, want the too small message", view)
+	}
+	next, _ = next.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	if view := next.View(); strings.Contains(view, "Terminal too small") || !strings.Contains(view, "> ") {
+		t.Errorf("View() after resizing back = %q, want the chat", view)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// StreamingClient is a ChatClient that can also send responses back as they're
+// generated.
+type StreamingClient interface {
+	ChatClient
+	CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionStream, error)
+}
+
+// streamTimeouts returns how long a streamed request may take before its first
+// chunk arrives, ASCII_FIRST_TOKEN_SECONDS (default 15), and in total,
+// ASCII_TIMEOUT_SECONDS (default 120). A stream that's slow to start has
+// usually stalled, so it's given up on well before the total runs out.
+func streamTimeouts() (time.Duration, time.Duration) {
+	first := time.Duration(envInt("ASCII_FIRST_TOKEN_SECONDS", 15)) * time.Second
+	total := time.Duration(envInt("ASCII_TIMEOUT_SECONDS", 120)) * time.Second
+	return first, total
+}
+
+// completeStream streams a response and collects it into a completion, with a
+// separate error for a stream that never got going.
+// Each piece of text is also passed to onChunk as it arrives, when it's set.
+func completeStream(client StreamingClient, req openai.ChatCompletionRequest, onChunk func(string)) (completion, error) {
+	first, total := streamTimeouts()
+	ctx, cancel := context.WithTimeout(context.Background(), total)
+	defer cancel()
+	// Cancelled if nothing has arrived by the time it fires
+	var stalled atomic.Bool
+	timer := time.AfterFunc(first, func() {
+		stalled.Store(true)
+		cancel()
+	})
+	defer timer.Stop()
+
+	req.Stream = true
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stream, err := client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return completion{}, streamError(err, stalled.Load(), first, total)
+	}
+	defer stream.Close()
+
+	c := completion{Model: req.Model}
+	var text strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return completion{}, streamError(err, stalled.Load(), first, total)
+		}
+		timer.Stop()
+		if chunk.Model != "" {
+			c.Model = chunk.Model
+		}
+		if len(chunk.Choices) > 0 {
+			text.WriteString(chunk.Choices[0].Delta.Content)
+			if onChunk != nil {
+				onChunk(chunk.Choices[0].Delta.Content)
+			}
+			if reason := chunk.Choices[0].FinishReason; reason != "" {
+				c.FinishReason = string(reason)
+			}
+		}
+		if chunk.Usage != nil {
+			c.Tokens = chunk.Usage.TotalTokens
+			c.PromptTokens = chunk.Usage.PromptTokens
+			c.CompletionTokens = chunk.Usage.CompletionTokens
+		}
+	}
+	c.Text = text.String()
+	return c, nil
+}
+
+// streamError explains which of the timeouts ended a stream, if either did.
+func streamError(err error, stalled bool, first, total time.Duration) error {
+	if stalled {
+		return fmt.Errorf("openai didn't start responding within %s, the request looks stalled (ASCII_FIRST_TOKEN_SECONDS)", first)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("the response took longer than %s (ASCII_TIMEOUT_SECONDS)", total)
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// stallingStream returns a client for a server that sends the chunks in
+// start and then stops responding until the request is given up on.
+func stallingStream(t *testing.T, start ...openai.ChatCompletionStreamResponse) StreamingClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for _, chunk := range start {
+			b, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: %s\n\n", b)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL + "/v1"
+	return openai.NewClientWithConfig(config)
+}
+
+func TestStreamTimeouts(t *testing.T) {
+	t.Setenv("ASCII_FIRST_TOKEN_SECONDS", "")
+	t.Setenv("ASCII_TIMEOUT_SECONDS", "")
+	if first, total := streamTimeouts(); first != 15*time.Second || total != 120*time.Second {
+		t.Errorf("streamTimeouts() = %s, %s, want the defaults", first, total)
+	}
+}
+
+func TestCompleteStreamSlowFirstChunk(t *testing.T) {
+	testEnv(t)
+	t.Setenv("ASCII_FIRST_TOKEN_SECONDS", "1")
+	t.Setenv("ASCII_TIMEOUT_SECONDS", "30")
+	start := time.Now()
+	_, err := completeStream(stallingStream(t), openai.ChatCompletionRequest{Model: "gpt-4o"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "didn't start responding within 1s") {
+		t.Fatalf("error = %v, want the first chunk timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("gave up after %s, want about a second", elapsed)
+	}
+}
+
+func TestCompleteStreamTotalTimeout(t *testing.T) {
+	testEnv(t)
+	t.Setenv("ASCII_FIRST_TOKEN_SECONDS", "30")
+	t.Setenv("ASCII_TIMEOUT_SECONDS", "1")
+	_, err := completeStream(stallingStream(t, chunk("```\n", "")), openai.ChatCompletionRequest{Model: "gpt-4o"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "took longer than 1s") {
+		t.Fatalf("error = %v, want the total timeout", err)
+	}
+}
+
+func TestCompleteStreamFirstChunkInTime(t *testing.T) {
+	testEnv(t)
+	// A first chunk stops the first token timer, so a short one doesn't end
+	// a response that's still arriving
+	t.Setenv("ASCII_FIRST_TOKEN_SECONDS", "1")
+	t.Setenv("ASCII_TIMEOUT_SECONDS", "2")
+	_, err := completeStream(stallingStream(t, chunk("```\n", "")), openai.ChatCompletionRequest{Model: "gpt-4o"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "took longer than 2s") {
+		t.Fatalf("error = %v, want the total timeout rather than the first chunk one", err)
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"slices"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const defaultStyle = "ascii"
+
+// artStyles are the kinds of art the model can be steered towards, in the
+// order alt+s cycles through them, with the instruction sent for each.
+var artStyles = []string{defaultStyle, "blocks", "emoji"}
+
+var styleHints = map[string]string{
+	defaultStyle: "Draw art using only plain ASCII characters.",
+	"blocks":     "Draw art using unicode block and box drawing characters like █ ▓ ▒ ░ ▀ ▄ ─ │ for solid shading.",
+	"emoji":      "Draw art using emoji as the building blocks, laid out in a grid.",
+}
+
+// envStyle returns the style set by ASCII_STYLE, or plain ascii when it's
+// unset or not one of the known styles.
+func envStyle() string {
+	if style := os.Getenv("ASCII_STYLE"); slices.Contains(artStyles, style) {
+		return style
+	}
+	return defaultStyle
+}
+
+// nextStyle returns the style after style in artStyles, wrapping around.
+func nextStyle(style string) string {
+	return artStyles[(slices.Index(artStyles, style)+1)%len(artStyles)]
+}
+
+// withStyle puts the instruction for style in front of history as a system
+// message, leaving history itself untouched.
+func withStyle(history []openai.ChatCompletionMessage, style string) []openai.ChatCompletionMessage {
+	hint, ok := styleHints[style]
+	if !ok {
+		return history
+	}
+	return append([]openai.ChatCompletionMessage{{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: hint,
+	}}, history...)
+}
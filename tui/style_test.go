@@ -0,0 +1,56 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestEnvStyle(t *testing.T) {
+	for value, want := range map[string]string{"": defaultStyle, "blocks": "blocks", "emoji": "emoji", "crayon": defaultStyle} {
+		t.Setenv("ASCII_STYLE", value)
+		if got := envStyle(); got != want {
+			t.Errorf("envStyle() = %q with ASCII_STYLE=%q, want %q", got, value, want)
+		}
+	}
+}
+
+func TestNextStyle(t *testing.T) {
+	style := defaultStyle
+	for _, want := range []string{"blocks", "emoji", defaultStyle} {
+		if style = nextStyle(style); style != want {
+			t.Errorf("nextStyle() = %q, want %q", style, want)
+		}
+	}
+}
+
+func TestStyleReachesRequest(t *testing.T) {
+	client := answering("```\n██\n```")
+	m := newTestChat(t, client)
+	t.Setenv("ASCII_STYLE", "")
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s"), Alt: true})
+	m = next.(chatModel)
+	m.textarea.SetValue("a cat")
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	next, _ = next.Update(awaitMsg[responseMsg](t, cmd))
+	m = next.(chatModel)
+
+	sent := client.sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(sent))
+	}
+	messages := sent[0].Messages
+	if len(messages) != 2 || messages[0].Role != openai.ChatMessageRoleSystem || messages[0].Content != styleHints["blocks"] {
+		t.Errorf("messages = %+v, want the blocks hint in front of the prompt", messages)
+	}
+	if len(m.history) != 2 || m.history[0].Role != openai.ChatMessageRoleUser {
+		t.Errorf("history = %+v, want the hint kept out of it", m.history)
+	}
+	if record := m.artRecord(m.ascii.art, m.ascii.seed); record.Style != "blocks" {
+		t.Errorf("saved style = %q, want blocks", record.Style)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+// summaryPrompt asks for a look back over the session so far.
+const summaryPrompt = "Summarize this session so far for me to look back on. List each piece of art you made with a short title, and the prompts that shaped them the most. Don't draw any art."
+
+// summaryMsg is the answer to a request for a summary of the session.
+type summaryMsg struct {
+	id   int
+	resp completion
+	err  error
+}
+
+// summaryHistory is the conversation with the request for a summary added,
+// leaving the history itself alone.
+func summaryHistory(history []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	return append(slices.Clone(history), openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: summaryPrompt,
+	})
+}
+
+// summarize asks the model to sum up the session. Unlike other requests the
+// question and its answer aren't added to the history, so they don't steer
+// what comes after.
+func (m *chatModel) summarize() tea.Cmd {
+	m.waiting = true
+	m.requests++
+	m.thinkingIndex = 0
+	chat := *m
+	history := summaryHistory(m.history)
+	id := m.requests
+	return tea.Batch(func() tea.Msg {
+		resp, err := chat.SendMessage(history)
+		return summaryMsg{id: id, resp: resp, err: err}
+	}, thinkingTick(id))
+}
+
+// showSummary puts the summary in the transcript.
+func (m *chatModel) showSummary(msg summaryMsg) {
+	m.waiting = false
+	if msg.err != nil {
+		m.messages = append(m.messages, statusStyle.Render(fmt.Sprintf("Could not summarize the session: %v", msg.err)))
+	} else {
+		if requestCost, ok := cost(msg.resp); ok {
+			m.sessionCost += requestCost
+		}
+		m.messages = append(m.messages, m.senderStyle.Render("Session summary:")+"\n"+msg.resp.Text)
+	}
+	m.refresh()
+}
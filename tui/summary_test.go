@@ -0,0 +1,62 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestChatSummary(t *testing.T) {
+	client := answering("Here:\n```\n=^.^=\n```")
+	m := sendThrough(t, newTestChat(t, client), "a cat")
+	client.resp.Choices[0].Message.Content = "1. A cat"
+
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w"), Alt: true})
+	if cmd == nil || !next.(chatModel).waiting {
+		t.Fatal("alt+w didn't ask for a summary")
+	}
+	next, _ = next.Update(awaitMsg[summaryMsg](t, cmd))
+	m = next.(chatModel)
+
+	sent := client.sent()
+	if len(sent) != 2 {
+		t.Fatalf("sent %d requests, want the prompt and the summary", len(sent))
+	}
+	var contents []string
+	for _, message := range sent[1].Messages {
+		if message.Role != openai.ChatMessageRoleSystem {
+			contents = append(contents, message.Content)
+		}
+	}
+	want := []string{"a cat", "Here:\n```\n=^.^=\n```", summaryPrompt}
+	if strings.Join(contents, "|") != strings.Join(want, "|") {
+		t.Errorf("summary request = %q, want the transcript followed by the summary prompt", contents)
+	}
+	if len(m.history) != 2 {
+		t.Errorf("history = %+v, want the summary kept out of it", m.history)
+	}
+	if last := m.messages[len(m.messages)-1]; !strings.Contains(last, "Session summary:") || !strings.Contains(last, "1. A cat") {
+		t.Errorf("last message = %q, want the summary shown", last)
+	}
+}
+
+func TestChatSummaryEmpty(t *testing.T) {
+	client := answering("unused")
+	m := newTestChat(t, client)
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w"), Alt: true}); cmd != nil {
+		t.Error("asked for a summary of an empty session")
+	}
+}
+
+func TestSummaryHistory(t *testing.T) {
+	history := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "a cat"}}
+	got := summaryHistory(history[:1:1])
+	if len(got) != 2 || got[1].Content != summaryPrompt || len(history) != 1 {
+		t.Errorf("summaryHistory() = %+v, want the prompt added to a copy", got)
+	}
+}
@@ -0,0 +1,22 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"math/rand/v2"
+)
+
+// The words surprise prompts are made from when none are configured.
+var (
+	surpriseSubjects = []string{"a lighthouse", "a sleeping cat", "a dragon", "a robot", "a sailing ship", "an owl", "a castle", "a cactus", "a rocket", "a jellyfish", "a mountain cabin", "a bicycle"}
+	surpriseStyles   = []string{"minimalist", "retro", "spooky", "cute", "geometric", "detailed", "cartoon", "art deco", "pixelated", "mysterious"}
+)
+
+// surprisePrompt makes up a prompt from a random subject and style, taken
+// from ASCII_SURPRISE_SUBJECTS and ASCII_SURPRISE_STYLES.
+func surprisePrompt() string {
+	subjects := envList("ASCII_SURPRISE_SUBJECTS", surpriseSubjects)
+	styles := envList("ASCII_SURPRISE_STYLES", surpriseStyles)
+	return "Draw " + subjects[rand.IntN(len(subjects))] + " in a " + styles[rand.IntN(len(styles))] + " style"
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestSurprisePrompt(t *testing.T) {
+	t.Run("built in words", func(t *testing.T) {
+		t.Setenv("ASCII_SURPRISE_SUBJECTS", "")
+		t.Setenv("ASCII_SURPRISE_STYLES", " , ")
+		for range 50 {
+			prompt := surprisePrompt()
+			if strings.TrimSpace(prompt) == "" {
+				t.Fatal("surprisePrompt() is empty")
+			}
+			subject, style, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(prompt, "Draw "), " style"), " in a ")
+			if !ok || !slices.Contains(surpriseSubjects, subject) || !slices.Contains(surpriseStyles, style) {
+				t.Fatalf("surprisePrompt() = %q, want a built in subject and style", prompt)
+			}
+		}
+	})
+	t.Run("configured words", func(t *testing.T) {
+		t.Setenv("ASCII_SURPRISE_SUBJECTS", " a teapot ")
+		t.Setenv("ASCII_SURPRISE_STYLES", "steampunk")
+		if got, want := surprisePrompt(), "Draw a teapot in a steampunk style"; got != want {
+			t.Errorf("surprisePrompt() = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestChatSurpriseMe(t *testing.T) {
+	t.Setenv("ASCII_SURPRISE_SUBJECTS", "a teapot")
+	t.Setenv("ASCII_SURPRISE_STYLES", "steampunk")
+	client := answering("```\n c[_]\n```")
+	m := newTestChat(t, client)
+	m.textarea.SetValue("half typed")
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("z"), Alt: true})
+	for next.(chatModel).waiting {
+		next, cmd = next.Update(awaitMsg[responseMsg](t, cmd))
+	}
+	m = next.(chatModel)
+
+	const prompt = "Draw a teapot in a steampunk style"
+	sent := client.sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(sent))
+	}
+	last := sent[0].Messages[len(sent[0].Messages)-1]
+	if last.Role != openai.ChatMessageRoleUser || last.Content != prompt {
+		t.Errorf("sent %+v, want the surprise prompt in place of what was typed", last)
+	}
+	if !strings.Contains(m.viewport.View(), prompt) {
+		t.Errorf("transcript doesn't show the prompt:\n%s", m.viewport.View())
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Measurements of a cell in the exported SVG, for a 14px monospace font.
+const (
+	svgFontSize   = 14.0
+	svgCellWidth  = svgFontSize * 0.6
+	svgLineHeight = svgFontSize * 1.2
+	svgPadding    = svgFontSize
+)
+
+// SVG renders art as a vector image, one text element per line in a
+// monospace font, with the canvas sized to the widest line so lines of any
+// length fit. Spacing is preserved so the art keeps its shape.
+func SVG(art string, foreground string, background string) string {
+	// Escape sequences aren't valid in SVG text, and the colors come from the
+	// flags instead
+	lines := strings.Split(plainArt(art), "\n")
+	cols := 0
+	for _, line := range lines {
+		cols = max(cols, runewidth.StringWidth(line))
+	}
+	width := float64(cols)*svgCellWidth + 2*svgPadding
+	height := float64(len(lines))*svgLineHeight + 2*svgPadding
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, `  <rect width="100%%" height="100%%" fill="%s"/>`+"\n", escapeXML(background))
+	fmt.Fprintf(&b, `  <g font-family="ui-monospace, Menlo, Consolas, monospace" font-size="%.0f" fill="%s" xml:space="preserve">`+"\n", svgFontSize, escapeXML(foreground))
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		// Text sits on its baseline, about 80% of the way down its line
+		y := svgPadding + float64(i)*svgLineHeight + svgFontSize
+		fmt.Fprintf(&b, `    <text x="%.0f" y="%.1f">%s</text>`+"\n", svgPadding, y, escapeXML(line))
+	}
+	b.WriteString("  </g>\n</svg>\n")
+	return b.String()
+}
+
+// AnimatedSVG renders each piece of art as a frame of an animation, shown for
+// delay each and looping forever. The canvas fits the largest frame, smaller
+// ones are drawn from its top left corner.
+func AnimatedSVG(arts []string, foreground string, background string, delay time.Duration) string {
+	frames := make([][]string, len(arts))
+	cols, rows := 0, 0
+	for i, art := range arts {
+		frames[i] = strings.Split(plainArt(art), "\n")
+		for _, line := range frames[i] {
+			cols = max(cols, runewidth.StringWidth(line))
+		}
+		rows = max(rows, len(frames[i]))
+	}
+	width := float64(cols)*svgCellWidth + 2*svgPadding
+	height := float64(rows)*svgLineHeight + 2*svgPadding
+	n := float64(len(frames))
+	total := max(time.Millisecond, delay*time.Duration(len(frames)))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, `  <rect width="100%%" height="100%%" fill="%s"/>`+"\n", escapeXML(background))
+	fmt.Fprintf(&b, `  <g font-family="ui-monospace, Menlo, Consolas, monospace" font-size="%.0f" fill="%s" xml:space="preserve">`+"\n", svgFontSize, escapeXML(foreground))
+	for i, lines := range frames {
+		// Each frame is only visible for its slice of the loop
+		values, keyTimes := []string{}, []string{}
+		if i > 0 {
+			values, keyTimes = append(values, "hidden"), append(keyTimes, "0")
+		}
+		values, keyTimes = append(values, "visible"), append(keyTimes, fmt.Sprintf("%.4f", float64(i)/n))
+		if i < len(frames)-1 {
+			values, keyTimes = append(values, "hidden"), append(keyTimes, fmt.Sprintf("%.4f", float64(i+1)/n))
+		}
+		b.WriteString("    <g visibility=\"hidden\">\n")
+		fmt.Fprintf(&b, `      <animate attributeName="visibility" values="%s" keyTimes="%s" dur="%dms" calcMode="discrete" repeatCount="indefinite"/>`+"\n",
+			strings.Join(values, ";"), strings.Join(keyTimes, ";"), total.Milliseconds())
+		for row, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			y := svgPadding + float64(row)*svgLineHeight + svgFontSize
+			fmt.Fprintf(&b, `      <text x="%.0f" y="%.1f">%s</text>`+"\n", svgPadding, y, escapeXML(line))
+		}
+		b.WriteString("    </g>\n")
+	}
+	b.WriteString("  </g>\n</svg>\n")
+	return b.String()
+}
+
+// saveAnimation writes the art made in a session as an animated SVG in
+// ASCII_TRANSCRIPT_DIR, or the current directory, showing each piece for
+// ASCII_FRAME_MS (default 1000), and returns its path.
+func saveAnimation(arts []string) (string, error) {
+	dir := os.Getenv("ASCII_TRANSCRIPT_DIR")
+	if dir == "" {
+		dir = "."
+	}
+	delay := time.Duration(max(1, envInt("ASCII_FRAME_MS", 1000))) * time.Millisecond
+	path := filepath.Join(dir, "session-"+time.Now().Format("20060102-150405")+".svg")
+	return path, writeFile(path, []byte(AnimatedSVG(arts, "#e6e6e6", "#1e1e1e", delay)), 0o644)
+}
+
+// escapeXML makes s safe to put in SVG text or an attribute.
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// PlainText returns art as it's written to a text file, without fences or
+// colors and ending in a newline.
+func PlainText(art string) string {
+	return plainArt(art) + "\n"
+}
@@ -0,0 +1,45 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// thinkingMsg moves the status shown while waiting on a response along. It
+// carries the id of the request it's for, so ticks for an earlier request stop.
+type thinkingMsg int
+
+// thinkingMessages returns the statuses cycled through while waiting on a
+// response, set by a comma separated ASCII_THINKING. Setting it to nothing
+// turns them off.
+func thinkingMessages() []string {
+	list, ok := os.LookupEnv("ASCII_THINKING")
+	if !ok {
+		return []string{"drawing...", "sketching...", "inking...", "shading..."}
+	}
+	messages := []string{}
+	for _, message := range strings.Split(list, ",") {
+		if message = strings.TrimSpace(message); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// thinkingTick moves to the next status after ASCII_THINKING_MS, 800 by
+// default.
+func thinkingTick(id int) tea.Cmd {
+	if len(thinkingMessages()) == 0 {
+		return nil
+	}
+	d := time.Duration(max(1, envInt("ASCII_THINKING_MS", 800))) * time.Millisecond
+	return tea.Tick(d, func(time.Time) tea.Msg {
+		return thinkingMsg(id)
+	})
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// streamBuffer holds streamed text as it arrives so it can be shown at a
+// readable pace. It's written to by the request and read by the chat.
+type streamBuffer struct {
+	mu       sync.Mutex
+	text     []rune
+	released int
+}
+
+func (b *streamBuffer) write(chunk string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.text = append(b.text, []rune(chunk)...)
+}
+
+// release lets up to n more characters of what's arrived through.
+func (b *streamBuffer) release(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.released = min(len(b.text), b.released+n)
+}
+
+// revealed returns the text that's been let through so far.
+func (b *streamBuffer) revealed() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.text[:b.released])
+}
+
+// caughtUp reports whether everything that's arrived has been let through.
+func (b *streamBuffer) caughtUp() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.released == len(b.text)
+}
+
+// streamRate is how many characters of a streamed response are shown a
+// second, ASCII_STREAM_CPS. 0, the default, shows the response all at once
+// when it's done.
+func streamRate() int {
+	return max(0, envInt("ASCII_STREAM_CPS", 0))
+}
+
+// throttled reports whether responses from client are streamed into the chat
+// at streamRate.
+func throttled(client ChatClient) bool {
+	_, ok := client.(StreamingClient)
+	return ok && envBool("ASCII_STREAM", false) && streamRate() > 0
+}
+
+// streamStep returns how often more of a streamed response is shown, and how
+// many characters each time, to keep to streamRate without redrawing more
+// than the renderer can.
+func streamStep() (time.Duration, int) {
+	rate := max(1, streamRate())
+	interval := max(time.Second/time.Duration(rate), time.Second/30)
+	// Rounded, since the interval is rounded down to whole nanoseconds
+	return interval, max(1, int((time.Duration(rate)*interval+time.Second/2)/time.Second))
+}
+
+// streamMsg shows more of the response to the request with its id.
+type streamMsg int
+
+func streamTick(id int) tea.Cmd {
+	interval, _ := streamStep()
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return streamMsg(id)
+	})
+}
+
+// streaming is the part of the response shown so far, as a message for the
+// transcript.
+func (m chatModel) streaming() string {
+	return m.senderStyle.Render(assistantLabel() + ": " + strings.TrimLeft(m.stream.revealed(), "\n"))
+}
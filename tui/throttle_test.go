@@ -0,0 +1,157 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestStreamBuffer(t *testing.T) {
+	b := &streamBuffer{}
+	if !b.caughtUp() || b.revealed() != "" {
+		t.Fatal("an empty buffer has something to show")
+	}
+	b.write("héllo")
+	if b.caughtUp() || b.revealed() != "" {
+		t.Errorf("revealed %q before anything was released", b.revealed())
+	}
+	// Characters are let through whole, however many bytes they take
+	b.release(2)
+	if got := b.revealed(); got != "hé" {
+		t.Errorf("revealed %q, want the first two characters", got)
+	}
+	b.write(" wörld")
+	b.release(4)
+	if got := b.revealed(); got != "héllo " || b.caughtUp() {
+		t.Errorf("revealed %q, caught up %v, want the rest held back", got, b.caughtUp())
+	}
+	// Releasing more than has arrived stops at what's there
+	b.release(100)
+	if got := b.revealed(); got != "héllo wörld" || !b.caughtUp() {
+		t.Errorf("revealed %q, caught up %v, want all of it", got, b.caughtUp())
+	}
+	b.write("!")
+	if b.caughtUp() {
+		t.Error("caught up with text that arrived after the last release")
+	}
+}
+
+func TestStreamStep(t *testing.T) {
+	tests := []struct {
+		cps      string
+		interval time.Duration
+		step     int
+	}{
+		{cps: "1", interval: time.Second, step: 1},
+		{cps: "10", interval: 100 * time.Millisecond, step: 1},
+		{cps: "20", interval: 50 * time.Millisecond, step: 1},
+		// Faster than the renderer, so more characters a tick instead
+		{cps: "300", interval: time.Second / 30, step: 10},
+		{cps: "3000", interval: time.Second / 30, step: 100},
+		{cps: "0", interval: time.Second, step: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cps, func(t *testing.T) {
+			t.Setenv("ASCII_STREAM_CPS", tt.cps)
+			interval, step := streamStep()
+			if interval != tt.interval || step != tt.step {
+				t.Errorf("streamStep() = %s, %d, want %s, %d", interval, step, tt.interval, tt.step)
+			}
+		})
+	}
+}
+
+func TestThrottled(t *testing.T) {
+	tests := []struct {
+		name   string
+		client ChatClient
+		stream string
+		cps    string
+		want   bool
+	}{
+		{name: "streaming with a cap", client: streaming(t), stream: "true", cps: "50", want: true},
+		{name: "no cap", client: streaming(t), stream: "true", cps: "0", want: false},
+		{name: "streaming off", client: streaming(t), stream: "false", cps: "50", want: false},
+		{name: "client can't stream", client: answering("hi"), stream: "true", cps: "50", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASCII_STREAM", tt.stream)
+			t.Setenv("ASCII_STREAM_CPS", tt.cps)
+			if got := throttled(tt.client); got != tt.want {
+				t.Errorf("throttled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatThrottledStream(t *testing.T) {
+	client := streaming(t,
+		chunk("Here:\n", ""),
+		chunk("```\n(o.o)\n", ""),
+		chunk("```", openai.FinishReasonStop),
+	)
+	m := newTestChat(t, client)
+	t.Setenv("ASCII_STREAM", "true")
+	t.Setenv("ASCII_STREAM_CPS", "10")
+	const text = "Here:\n```\n(o.o)\n```"
+
+	m.textarea.SetValue("a face")
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(chatModel)
+	if m.stream == nil {
+		t.Fatal("no buffer for the response with a cap set")
+	}
+	next, _ = m.Update(awaitMsg[responseMsg](t, cmd))
+	m = next.(chatModel)
+	// The whole response is in, but it waits for the buffer to be shown
+	if m.held == nil || !m.waiting || m.ascii != nil {
+		t.Fatalf("held %v, waiting %v, art %v, want the response held back", m.held, m.waiting, m.ascii)
+	}
+	if strings.Contains(ansi.Strip(m.transcript()), "(o.o)") {
+		t.Error("shown the art before it was released")
+	}
+
+	// A tick for an earlier request shows nothing more
+	next, _ = m.Update(streamMsg(m.requests - 1))
+	m = next.(chatModel)
+	if m.stream.revealed() != "" {
+		t.Errorf("revealed %q on a stale tick", m.stream.revealed())
+	}
+
+	ticks := 0
+	for m.waiting {
+		next, cmd = m.Update(streamMsg(m.requests))
+		m = next.(chatModel)
+		ticks++
+		if !m.waiting {
+			break
+		}
+		revealed := m.stream.revealed()
+		if want := string([]rune(text)[:ticks]); revealed != want {
+			t.Fatalf("revealed %q after %d ticks, want %q", revealed, ticks, want)
+		}
+		if cmd == nil {
+			t.Fatal("no tick for the rest of the response")
+		}
+		if ticks == 3 && !strings.Contains(ansi.Strip(m.transcript()), assistantLabel()+": Her") {
+			t.Errorf("transcript doesn't show the part released:\n%s", ansi.Strip(m.transcript()))
+		}
+	}
+	if ticks != len([]rune(text)) {
+		t.Errorf("took %d ticks, want one for each of the %d characters", ticks, len([]rune(text)))
+	}
+	if m.stream != nil || m.held != nil {
+		t.Error("the buffer outlived the response")
+	}
+	if m.ascii == nil || m.ascii.art != "```\n(o.o)\n```" {
+		t.Errorf("art = %+v, want the response handled once it was all shown", m.ascii)
+	}
+}
@@ -0,0 +1,296 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/sashabaranov/go-openai"
+)
+
+// formatTranscript joins the chat messages for display, fitting prose lines
+// longer than width by wrapping them at word boundaries, or cutting them off
+// with an ellipsis when truncate is set. Prose is shifted right by indent
+// columns, to center it in a wider chat. Lines inside art are left alone so
+// the art keeps its shape.
+func formatTranscript(messages []string, width int, indent int, truncate bool) string {
+	lines := []string{}
+	for _, message := range messages {
+		lines = append(lines, formatMessage(message, width, indent, truncate)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatMessage fits one message for display, see formatTranscript.
+func formatMessage(message string, width int, indent int, truncate bool) []string {
+	margin := strings.Repeat(" ", max(0, indent))
+	if message == dividerMessage {
+		return []string{margin + statusStyle.Render(strings.Repeat("─", max(1, width)))}
+	}
+	if prompt, ok := strings.CutPrefix(message, captionPrefix); ok {
+		message = statusStyle.Render("prompt: " + strings.Join(strings.Fields(prompt), " "))
+	}
+	lines := []string{}
+	split := strings.Split(message, "\n")
+	art := artLines(split)
+	for i, line := range split {
+		if art[i] {
+			lines = append(lines, line)
+			continue
+		}
+		if width >= 1 && ansi.StringWidth(line) > width {
+			if truncate {
+				line = ansi.Truncate(line, width, "…")
+			} else {
+				line = ansi.Wrap(line, width, "")
+			}
+		}
+		for _, l := range strings.Split(line, "\n") {
+			lines = append(lines, margin+l)
+		}
+	}
+	return lines
+}
+
+// artLines reports which lines of a message are art, to be left as they are.
+// Those are the lines of a block fenced with any of the ASCII_FENCES markers,
+// fences included, and those of an indented code block at least two lines
+// long, the same blocks extractArt finds art in.
+func artLines(lines []string) []bool {
+	art := make([]bool, len(lines))
+	markers := fenceMarkers()
+	inArt := false
+	for i, line := range lines {
+		plain := ansi.Strip(line)
+		toggles := false
+		for _, marker := range markers {
+			if strings.Count(plain, marker)%2 == 1 {
+				toggles = true
+			}
+		}
+		if toggles {
+			inArt = !inArt
+		}
+		art[i] = inArt || toggles
+	}
+	indented := func(i int) bool {
+		plain := ansi.Strip(lines[i])
+		return !art[i] && strings.TrimSpace(plain) != "" && (strings.HasPrefix(plain, "    ") || strings.HasPrefix(plain, "\t"))
+	}
+	for start := 0; start < len(lines); start++ {
+		end := start
+		for end < len(lines) && indented(end) {
+			end++
+		}
+		if end-start >= 2 {
+			for i := start; i < end; i++ {
+				art[i] = true
+			}
+		}
+		start = end
+	}
+	return art
+}
+
+// transcriptCache keeps each message as it was last formatted, so a long
+// conversation isn't wrapped all over again every time a message is added.
+// Only new or changed messages, or all of them after a resize, are formatted.
+type transcriptCache struct {
+	width    int
+	indent   int
+	truncate bool
+	messages []string
+	lines    [][]string
+}
+
+// format is formatTranscript, reusing what's already been formatted.
+func (c *transcriptCache) format(messages []string, width int, indent int, truncate bool) string {
+	if c.width != width || c.indent != indent || c.truncate != truncate {
+		c.width, c.indent, c.truncate = width, indent, truncate
+		c.messages, c.lines = nil, nil
+	}
+	total := 0
+	for i, message := range messages {
+		if i >= len(c.messages) {
+			c.messages = append(c.messages, message)
+			c.lines = append(c.lines, formatMessage(message, width, indent, truncate))
+		} else if c.messages[i] != message {
+			c.messages[i] = message
+			c.lines[i] = formatMessage(message, width, indent, truncate)
+		}
+		total += len(c.lines[i])
+	}
+	// Messages may have been dropped, e.g. by switching branches
+	c.messages, c.lines = c.messages[:len(messages)], c.lines[:len(messages)]
+	lines := make([]string, 0, total)
+	for _, message := range c.lines {
+		lines = append(lines, message...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dividerMessage stands in the chat messages for a divider between topics. It's
+// drawn as a rule across the transcript, and only ever shown.
+const dividerMessage = "\x00divider"
+
+// captionPrefix starts a message that captions the art above it with the
+// prompt it was made from. Captions are only shown while they're turned on.
+const captionPrefix = "\x00caption:"
+
+// withoutCaptions leaves the captions out of messages.
+func withoutCaptions(messages []string) []string {
+	kept := make([]string, 0, len(messages))
+	for _, message := range messages {
+		if !strings.HasPrefix(message, captionPrefix) {
+			kept = append(kept, message)
+		}
+	}
+	return kept
+}
+
+// markdownRule marks a divider in a saved transcript.
+const markdownRule = "---"
+
+// roleHeading marks each turn in a markdown transcript with the role it was
+// sent as, e.g. "## user". The chat's labels are only for showing the
+// conversation, so transcripts read back the same whatever they're set to.
+func roleHeading(role string) string {
+	return "## " + role
+}
+
+// turn is one message in a markdown transcript.
+type turn struct {
+	role string
+	text string
+}
+
+// transcriptMarkdown writes the conversation history as markdown, one heading
+// per turn. A rule is put in before the messages at each index in dividers,
+// and at the end for any past the last message.
+func transcriptMarkdown(history []openai.ChatCompletionMessage, dividers []int) string {
+	var md strings.Builder
+	md.WriteString("# ascii transcript\n")
+	rules := func(at int, last bool) {
+		for _, d := range dividers {
+			if d == at || last && d > at {
+				md.WriteString("\n" + markdownRule + "\n")
+			}
+		}
+	}
+	for i, message := range history {
+		rules(i, false)
+		md.WriteString("\n" + roleHeading(message.Role) + "\n\n" + strings.TrimRight(message.Content, "\n") + "\n")
+	}
+	rules(len(history), true)
+	return md.String()
+}
+
+// saveTranscript writes the conversation to a markdown file in
+// ASCII_TRANSCRIPT_DIR, or the current directory, and returns its path.
+func saveTranscript(history []openai.ChatCompletionMessage, dividers []int) (string, error) {
+	dir := os.Getenv("ASCII_TRANSCRIPT_DIR")
+	if dir == "" {
+		dir = "."
+	}
+	path := filepath.Join(dir, "transcript-"+time.Now().Format("20060102-150405")+".md")
+	return path, writeFile(path, []byte(transcriptMarkdown(history, dividers)), 0o644)
+}
+
+// transcriptCopiedMsg reports how copying the transcript went, and how big
+// it was.
+type transcriptCopiedMsg struct {
+	size int
+	err  error
+}
+
+// copyTranscript puts the conversation on the clipboard as markdown. It runs
+// in the background, so a long transcript doesn't hold the chat up while the
+// clipboard takes it.
+func copyTranscript(history []openai.ChatCompletionMessage, dividers []int) tea.Cmd {
+	md := transcriptMarkdown(history, dividers)
+	return func() tea.Msg {
+		return transcriptCopiedMsg{size: len(md), err: clipboard.WriteAll(md)}
+	}
+}
+
+// status describes how copying the transcript went.
+func (msg transcriptCopiedMsg) status() string {
+	if msg.err != nil {
+		return fmt.Sprintf("Couldn't copy the transcript: %v", msg.err)
+	}
+	size := fmt.Sprintf("%d bytes", msg.size)
+	if msg.size >= 1024 {
+		size = fmt.Sprintf("%.1f KB", float64(msg.size)/1024)
+	}
+	return "Copied the transcript to the clipboard (" + size + ")"
+}
+
+// parseTranscript reads the turns back out of a markdown transcript. Text
+// before the first turn is ignored. Headings name the role of each turn.
+// Transcripts saved before that have the chat's labels instead, so the
+// user's label, or "You", counts as the user and any other second level
+// heading as the assistant, which keeps hand edited transcripts loading too.
+func parseTranscript(md string) ([]turn, error) {
+	turns := []turn{}
+	var text []string
+	flush := func() {
+		if len(turns) > 0 {
+			turns[len(turns)-1].text = strings.Trim(strings.Join(text, "\n"), "\n")
+		}
+		text = nil
+	}
+	inArt := false
+	for _, line := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		// Art may contain lines that look like headings
+		if strings.HasPrefix(strings.TrimSpace(line), fence) {
+			inArt = !inArt
+		}
+		if !inArt && strings.TrimSpace(line) == markdownRule {
+			// Dividers are only for reading
+			continue
+		}
+		if role, ok := headingRole(strings.TrimSpace(line)); ok && !inArt {
+			flush()
+			turns = append(turns, turn{role: role})
+			continue
+		}
+		text = append(text, line)
+	}
+	flush()
+	if len(turns) == 0 {
+		return nil, errors.New("no turns found, expected headings like \"" + roleHeading(openai.ChatMessageRoleUser) + "\"")
+	}
+	return turns, nil
+}
+
+// headingRole returns the role a turn's heading stands for. Only the role
+// headings and the labels older files were headed with start a turn, so a
+// heading in a message, like "## Explanation", stays part of it.
+func headingRole(heading string) (string, bool) {
+	for _, role := range []string{openai.ChatMessageRoleUser, openai.ChatMessageRoleAssistant, openai.ChatMessageRoleSystem} {
+		if heading == roleHeading(role) {
+			return role, true
+		}
+	}
+	if heading == "## "+userLabel() || heading == "## You" {
+		return openai.ChatMessageRoleUser, true
+	}
+	if heading == "## "+assistantLabel() {
+		return openai.ChatMessageRoleAssistant, true
+	}
+	for _, label := range providerLabels {
+		if heading == "## "+label {
+			return openai.ChatMessageRoleAssistant, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,331 @@
+/*
+Copyright © 2024 Eric Culley <https://github.com/ericulley>
+*/
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/sashabaranov/go-openai"
+)
+
+// testHistory has a message in each role, with art that has a line that
+// looks like a heading.
+var testHistory = []openai.ChatCompletionMessage{
+	{Role: openai.ChatMessageRoleSystem, Content: "Only draw cats."},
+	{Role: openai.ChatMessageRoleUser, Content: "a cat"},
+	{Role: openai.ChatMessageRoleAssistant, Content: "Here:\n```\n## =^.^=\n```"},
+	{Role: openai.ChatMessageRoleUser, Content: "another"},
+}
+
+func historyTurns(history []openai.ChatCompletionMessage) []turn {
+	turns := []turn{}
+	for _, message := range history {
+		turns = append(turns, turn{role: message.Role, text: message.Content})
+	}
+	return turns
+}
+
+func TestTranscriptRoundTrip(t *testing.T) {
+	tests := []struct {
+		name           string
+		userLabel      string
+		assistantLabel string
+		dividers       []int
+	}{
+		{name: "default labels"},
+		{name: "custom labels", userLabel: "Me", assistantLabel: "Bot"},
+		{name: "labels named like roles", userLabel: "assistant", assistantLabel: "user"},
+		{name: "dividers", dividers: []int{1, 3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASCII_USER_LABEL", tt.userLabel)
+			t.Setenv("ASCII_ASSISTANT_LABEL", tt.assistantLabel)
+			md := transcriptMarkdown(testHistory, tt.dividers)
+			for _, heading := range []string{"## system", "## user", "## assistant"} {
+				if !strings.Contains(md, "\n"+heading+"\n") {
+					t.Errorf("transcript has no %q heading:\n%s", heading, md)
+				}
+			}
+			turns, err := parseTranscript(md)
+			if err != nil {
+				t.Fatalf("parseTranscript() error = %v", err)
+			}
+			if want := historyTurns(testHistory); !reflect.DeepEqual(turns, want) {
+				t.Errorf("parseTranscript() = %+v, want %+v", turns, want)
+			}
+		})
+	}
+}
+
+func TestParseLabelledTranscript(t *testing.T) {
+	t.Setenv("ASCII_USER_LABEL", "Me")
+	md := "# ascii transcript\n\n## Me\n\na cat\n\n## ChatGPT\n\n```\n=^.^=\n```\n\n## You\n\nanother\n"
+	turns, err := parseTranscript(md)
+	if err != nil {
+		t.Fatalf("parseTranscript() error = %v", err)
+	}
+	want := []turn{
+		{role: openai.ChatMessageRoleUser, text: "a cat"},
+		{role: openai.ChatMessageRoleAssistant, text: "```\n=^.^=\n```"},
+		{role: openai.ChatMessageRoleUser, text: "another"},
+	}
+	if !reflect.DeepEqual(turns, want) {
+		t.Errorf("parseTranscript() = %+v, want %+v", turns, want)
+	}
+}
+
+func TestParseTranscriptWithoutTurns(t *testing.T) {
+	if _, err := parseTranscript("# ascii transcript\n\nnothing here\n"); err == nil {
+		t.Errorf("parseTranscript() of a transcript without turns didn't fail")
+	}
+}
+
+func TestConversationRoundTrip(t *testing.T) {
+	m := newTestChat(t, answering("ok"))
+	if err := saveConversation(m.conversation, testHistory); err != nil {
+		t.Fatalf("saveConversation() error = %v", err)
+	}
+	resumed, err := m.Resume(m.conversation)
+	if err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if !reflect.DeepEqual(resumed.history, testHistory) {
+		t.Errorf("resumed history = %+v, want %+v", resumed.history, testHistory)
+	}
+	if got := resumed.messages[0]; !strings.Contains(got, "(system): Only draw cats.") {
+		t.Errorf("system message shown as %q", got)
+	}
+}
+
+func TestResumeHeadingInReply(t *testing.T) {
+	history := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "a face"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "```\n(o.o)\n```\n\n## Explanation\n\nA face.\n\n### Made with\n\nParentheses."},
+		{Role: openai.ChatMessageRoleUser, Content: "## not a heading either"},
+	}
+	m := newTestChat(t, answering("ok"))
+	if err := saveConversation(m.conversation, history); err != nil {
+		t.Fatalf("saveConversation() error = %v", err)
+	}
+	resumed, err := m.Resume(m.conversation)
+	if err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if !reflect.DeepEqual(resumed.history, history) {
+		t.Errorf("resumed history = %+v, want %+v", resumed.history, history)
+	}
+
+	if err := saveRecovery(history); err != nil {
+		t.Fatalf("saveRecovery() error = %v", err)
+	}
+	if turns, ok := loadRecovery(); !ok || !reflect.DeepEqual(turns, historyTurns(history)) {
+		t.Errorf("loadRecovery() = %+v, want %+v", turns, historyTurns(history))
+	}
+}
+
+func TestRecoveryRoundTrip(t *testing.T) {
+	testEnv(t)
+	if err := saveRecovery(testHistory); err != nil {
+		t.Fatalf("saveRecovery() error = %v", err)
+	}
+	turns, ok := loadRecovery()
+	if !ok {
+		t.Fatalf("loadRecovery() found nothing")
+	}
+	if want := historyTurns(testHistory); !reflect.DeepEqual(turns, want) {
+		t.Errorf("loadRecovery() = %+v, want %+v", turns, want)
+	}
+}
+
+func TestReplaySkipsSystemTurns(t *testing.T) {
+	path := filepath.Join(testEnv(t), "transcript.md")
+	if err := writeFile(path, []byte(transcriptMarkdown(testHistory, nil)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewReplayModel(path)
+	if err != nil {
+		t.Fatalf("NewReplayModel() error = %v", err)
+	}
+	want := []exchange{
+		{prompt: "a cat", response: "Here:\n```\n## =^.^=\n```"},
+		{prompt: "another"},
+	}
+	if !reflect.DeepEqual(m.exchanges, want) {
+		t.Errorf("exchanges = %+v, want %+v", m.exchanges, want)
+	}
+}
+
+func TestReplayOnlySystemTurns(t *testing.T) {
+	path := filepath.Join(testEnv(t), "transcript.md")
+	history := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: "Only draw cats."}}
+	if err := writeFile(path, []byte(transcriptMarkdown(history, nil)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewReplayModel(path); err == nil || !strings.Contains(err.Error(), "nothing to replay") {
+		t.Errorf("NewReplayModel() error = %v, want one saying there's nothing to replay", err)
+	}
+}
+
+func TestTranscriptMarkdownDividers(t *testing.T) {
+	history := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "a cat"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "meow"},
+	}
+	tests := []struct {
+		name     string
+		dividers []int
+		want     string
+	}{
+		{name: "none", dividers: nil, want: "# ascii transcript\n\n## user\n\na cat\n\n## assistant\n\nmeow\n"},
+		{name: "between", dividers: []int{1}, want: "# ascii transcript\n\n## user\n\na cat\n\n---\n\n## assistant\n\nmeow\n"},
+		{name: "at the end", dividers: []int{2}, want: "# ascii transcript\n\n## user\n\na cat\n\n## assistant\n\nmeow\n\n---\n"},
+		{name: "past the end after a reset", dividers: []int{5}, want: "# ascii transcript\n\n## user\n\na cat\n\n## assistant\n\nmeow\n\n---\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transcriptMarkdown(history, tt.dividers); got != tt.want {
+				t.Errorf("transcriptMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatDividerDisplayOnly(t *testing.T) {
+	client := answering("meow")
+	m := sendThrough(t, newTestChat(t, client), "a cat")
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("-"), Alt: true})
+	m = next.(chatModel)
+	if cmd != nil || len(m.history) != 2 {
+		t.Fatalf("history = %+v after a divider, want it left alone", m.history)
+	}
+	if !strings.Contains(m.viewport.View(), strings.Repeat("─", 20)) {
+		t.Errorf("viewport doesn't show the divider:\n%s", m.viewport.View())
+	}
+
+	m = sendThrough(t, m, "a dog")
+	for _, message := range client.sent()[1].Messages {
+		if strings.Contains(message.Content, dividerMessage) || strings.Contains(message.Content, "──") {
+			t.Errorf("sent the divider in %+v", message)
+		}
+	}
+
+	if err := os.MkdirAll(os.Getenv("ASCII_TRANSCRIPT_DIR"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	m = next.(chatModel)
+	path, ok := strings.CutPrefix(m.status, "Saved transcript to ")
+	if !ok {
+		t.Fatalf("status = %q, want the transcript saved", m.status)
+	}
+	md, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(md), "meow\n\n---\n\n## user\n\na dog") {
+		t.Errorf("transcript doesn't have the rule between topics:\n%s", md)
+	}
+}
+
+func TestArtCaptions(t *testing.T) {
+	client := answering("```\n=^.^=\n```")
+	m := newTestChat(t, client)
+	m = sendThrough(t, m, "a  cat\nsitting down")
+	client.resp.Choices[0].Message.Content = "What kind of dog?"
+	m = sendThrough(t, m, "a dog")
+	client.resp.Choices[0].Message.Content = "```\nU・ᴥ・U\n```"
+	m = sendThrough(t, m, "a puppy")
+
+	captions := []string{}
+	for _, message := range m.messages {
+		if prompt, ok := strings.CutPrefix(message, captionPrefix); ok {
+			captions = append(captions, prompt)
+		}
+	}
+	// Replies without art go uncaptioned, and each art is paired with the
+	// prompt that was sent for it
+	if want := []string{"a  cat\nsitting down", "a puppy"}; !slices.Equal(captions, want) {
+		t.Errorf("captions = %q, want %q", captions, want)
+	}
+	for _, message := range m.history {
+		if strings.Contains(message.Content, captionPrefix) || strings.Contains(message.Content, "prompt: ") {
+			t.Errorf("caption saved in the history: %+v", message)
+		}
+	}
+
+	if transcript := ansi.Strip(m.transcript()); strings.Contains(transcript, "prompt: ") {
+		t.Errorf("captions shown while off:\n%s", transcript)
+	}
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n"), Alt: true})
+	m = next.(chatModel)
+	lines := strings.Split(ansi.Strip(m.transcript()), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	transcript := strings.Join(lines, "\n")
+	for _, want := range []string{"=^.^=\n```\nprompt: a cat sitting down", "U・ᴥ・U\n```\nprompt: a puppy"} {
+		if !strings.Contains(transcript, want) {
+			t.Errorf("transcript doesn't have %q under its art:\n%s", want, transcript)
+		}
+	}
+}
+
+func TestFormatMessageLeavesArt(t *testing.T) {
+	long := strings.Repeat("#", 30)
+	tests := []struct {
+		name    string
+		fences  string
+		message string
+		want    []string
+	}{
+		{name: "backtick fence", message: "```\n" + long + "\n```", want: []string{"```", long, "```"}},
+		{name: "tilde fence", fences: "~~~", message: "~~~\n" + long + "\n~~~", want: []string{"~~~", long, "~~~"}},
+		{name: "tilde fence with language tag", fences: "```,~~~", message: "~~~text\n" + long + "\n~~~", want: []string{"~~~text", long, "~~~"}},
+		{name: "indented block", message: "    " + long + "\n    " + long, want: []string{"    " + long, "    " + long}},
+		{name: "tab indented block", message: "\t" + long + "\n\t" + long, want: []string{"\t" + long, "\t" + long}},
+		{name: "one indented line", message: "    " + long, want: []string{"  ", "  " + strings.Repeat("#", 20), "  " + strings.Repeat("#", 10)}},
+		{name: "tildes by default", message: "~~~\n" + long + "\n~~~", want: []string{"  ~~~", "  " + strings.Repeat("#", 20), "  " + strings.Repeat("#", 10), "  ~~~"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASCII_FENCES", tt.fences)
+			if got := formatMessage(tt.message, 20, 2, false); !slices.Equal(got, tt.want) {
+				t.Errorf("formatMessage(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatMessageLongLines(t *testing.T) {
+	prose := "the quick brown fox jumps over the lazy dog"
+	art := strings.Repeat("/\\", 15)
+	tests := []struct {
+		name     string
+		message  string
+		truncate bool
+		want     []string
+	}{
+		{name: "fits", message: "a short line", want: []string{"  a short line"}},
+		{name: "wrapped between words", message: prose, want: []string{"  the quick brown fox", "  jumps over the lazy", "  dog"}},
+		{name: "long word broken up", message: strings.Repeat("x", 25), want: []string{"  " + strings.Repeat("x", 20), "  xxxxx"}},
+		{name: "truncated", message: prose, truncate: true, want: []string{"  the quick brown fox…"}},
+		{name: "fenced art left alone", message: prose + "\n```\n" + art + "\n```", want: []string{"  the quick brown fox", "  jumps over the lazy", "  dog", "```", art, "```"}},
+		{name: "fenced art not truncated", message: "```\n" + art + "\n```", truncate: true, want: []string{"```", art, "```"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatMessage(tt.message, 20, 2, tt.truncate); !slices.Equal(got, tt.want) {
+				t.Errorf("formatMessage(%q, truncate %v) = %q, want %q", tt.message, tt.truncate, got, tt.want)
+			}
+		})
+	}
+}